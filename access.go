@@ -0,0 +1,160 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// fieldRef is the location of a field within the binary representation of a struct.
+type fieldRef struct {
+	// offset is the offset from the start of the struct, in bytes.
+	offset int
+	// size is the size of the field, in bytes.
+	size int
+	typ  reflect.Type
+}
+
+// checkLen returns a *DecodeError wrapping io.ErrUnexpectedEOF if data is
+// too short to contain the field referred by the path within the struct type t.
+func (ref *fieldRef) checkLen(data []byte, t reflect.Type, path string) error {
+	if len(data) < ref.offset+ref.size {
+		return &DecodeError{Offset: int64(ref.offset), Need: ref.offset + ref.size, Have: len(data),
+			FieldPath: path, Type: t, Err: io.ErrUnexpectedEOF}
+	}
+	return nil
+}
+
+type pathKey struct {
+	t    reflect.Type
+	path string
+}
+
+// maxCachedPaths is the maximum number of the paths cached by a group.
+const maxCachedPaths = 1024
+
+// pathCache caches the fieldRefs resolved by a group by pathKey. The paths
+// beyond maxCachedPaths are resolved on every use, so the paths of untrusted
+// input can't grow the cache without bound.
+type pathCache struct {
+	refs sync.Map
+	n    atomic.Int32
+}
+
+// resolve returns the location of the field referred by the path within
+// the struct type t based on the given af, which is the one of the group.
+func (c *pathCache) resolve(t reflect.Type, af AlignFactor, path string) (*fieldRef, error) {
+	key := pathKey{t, path}
+	if ref, ok := c.refs.Load(key); ok {
+		return ref.(*fieldRef), nil
+	}
+	ref, err := resolvePath(t, af, path)
+	if err != nil {
+		return nil, err
+	}
+	if c.n.Add(1) <= maxCachedPaths {
+		c.refs.Store(key, ref)
+	}
+	return ref, nil
+}
+
+// resolvePath returns the location of the field referred by the path within
+// the struct type t based on the given af.
+//
+// The path is a dotted list of field names, each may be followed by constant
+// array indexes, e.g. "Hdr.Flags[2]" or "Items[1][0].Seq".
+func resolvePath(t reflect.Type, af AlignFactor, path string) (*fieldRef, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("alignbinary: field path of non-struct type %v", t)
+	}
+	if err := checkType(t, t.Name()); err != nil {
+		return nil, err
+	}
+	ref := &fieldRef{typ: t}
+	for _, name := range strings.Split(path, ".") {
+		var indexes string
+		if i := strings.IndexByte(name, '['); i >= 0 {
+			name, indexes = name[:i], name[i:]
+		}
+		if ref.typ.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("alignbinary: invalid path %q of %v: %v is not a struct", path, t, ref.typ)
+		}
+		f, ok := ref.typ.FieldByName(name)
+		if !ok || len(f.Index) != 1 {
+			return nil, fmt.Errorf("alignbinary: invalid path %q of %v: no field %v in %v", path, t, name, ref.typ)
+		}
+		st := structTyp{}
+		st.init(ref.typ, af)
+		ref.offset += int(st.fields[f.Index[0]])
+		ref.typ = f.Type
+		for indexes != "" {
+			end := strings.IndexByte(indexes, ']')
+			if indexes[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("alignbinary: invalid path %q of %v: malformed index", path, t)
+			}
+			i, err := strconv.Atoi(indexes[1:end])
+			if err != nil || ref.typ.Kind() != reflect.Array || i < 0 || i >= ref.typ.Len() {
+				return nil, fmt.Errorf("alignbinary: invalid path %q of %v: invalid index %v of %v", path, t, indexes[:end+1], ref.typ)
+			}
+			ref.typ = ref.typ.Elem()
+			elemSize := typeSize(ref.typ, af)
+			ref.offset += i * int(elemSize)
+			indexes = indexes[end+1:]
+		}
+	}
+	size := typeSize(ref.typ, af)
+	ref.size = int(size)
+	return ref, nil
+}
+
+// Get decodes the field referred by the path from the data encoded from a struct
+// of type t, without decoding the other fields, e.g.
+//
+//	flags, err := dg.Get(data, order, reflect.TypeOf(Msg{}), "Hdr.Flags[2]")
+//
+// The path is a dotted list of field names, each may be followed by constant
+// array indexes. The returned value has the type of the field.
+// It returns a *DecodeError wrapping io.ErrUnexpectedEOF if data is too short
+// to contain the field.
+func (dg *DecoderGroup) Get(data []byte, order binary.ByteOrder, t reflect.Type, path string) (interface{}, error) {
+	ref, err := dg.paths.resolve(t, dg.af, path)
+	if err != nil {
+		return nil, err
+	}
+	if err = ref.checkLen(data, t, path); err != nil {
+		return nil, err
+	}
+	v := reflect.New(ref.typ)
+	decoder, _ := dg.bindMsg(v.Interface(), order)
+	decoder(data[ref.offset:])
+	return v.Elem().Interface(), nil
+}
+
+// Set encodes the value into the field referred by the path within the data
+// encoded from a struct of type t, without touching the other fields, e.g.
+//
+//	err := eg.Set(data, order, reflect.TypeOf(Msg{}), "Hdr.Seq", uint32(7))
+//
+// The value must have the type of the field.
+// It returns a *DecodeError wrapping io.ErrUnexpectedEOF as Get does
+// if data is too short to contain the field.
+func (eg *EncoderGroup) Set(data []byte, order binary.ByteOrder, t reflect.Type, path string, value interface{}) error {
+	ref, err := eg.paths.resolve(t, eg.af, path)
+	if err != nil {
+		return err
+	}
+	if vt := reflect.TypeOf(value); vt != ref.typ {
+		return fmt.Errorf("alignbinary: cannot set %v of %v of type %v to a value of type %v", path, t, ref.typ, vt)
+	}
+	if err = ref.checkLen(data, t, path); err != nil {
+		return err
+	}
+	encoder, _ := eg.bindMsg(value, order)
+	encoder(data[ref.offset:])
+	return nil
+}
@@ -1,33 +1,156 @@
 package alignbinary
 
 import (
-	"io"
+	"context"
 	"encoding/binary"
+	"io"
+	"reflect"
+	"sync/atomic"
+	"unsafe"
 )
 
-var defaultEG = NewEncoderGroup(AlignDefault)
-var defaultDG = NewDecoderGroup(AlignDefault)
+var defaultEG atomic.Pointer[EncoderGroup]
+var defaultDG atomic.Pointer[DecoderGroup]
+
+func init() {
+	defaultEG.Store(NewEncoderGroup(AlignDefault))
+	defaultDG.Store(NewDecoderGroup(AlignDefault))
+}
+
+// DefaultEncoderGroup returns the encoder group used by the package level functions.
+func DefaultEncoderGroup() *EncoderGroup {
+	return defaultEG.Load()
+}
 
+// DefaultDecoderGroup returns the decoder group used by the package level functions.
+func DefaultDecoderGroup() *DecoderGroup {
+	return defaultDG.Load()
+}
+
+// ReplaceEncoderGroup replaces the encoder group used by the package level functions.
+// It's safe to call concurrently with the encoding.
 func ReplaceEncoderGroup(eg *EncoderGroup) {
-	defaultEG = eg
+	defaultEG.Store(eg)
 }
 
+// ReplaceDecoderGroup replaces the decoder group used by the package level functions.
+// It's safe to call concurrently with the decoding.
 func ReplaceDecoderGroup(dg *DecoderGroup) {
-	defaultDG = dg
+	defaultDG.Store(dg)
+}
+
+// groupsKey is the context key for the groups carried by a context.
+type groupsKey struct{}
+
+// groups are the groups carried by a context.
+type groups struct {
+	eg *EncoderGroup
+	dg *DecoderGroup
+}
+
+// ContextWithGroups returns a copy of ctx which carries eg and dg.
+// A nil group is inherited from ctx.
+func ContextWithGroups(ctx context.Context, eg *EncoderGroup, dg *DecoderGroup) context.Context {
+	if g, ok := ctx.Value(groupsKey{}).(groups); ok {
+		if eg == nil {
+			eg = g.eg
+		}
+		if dg == nil {
+			dg = g.dg
+		}
+	}
+	return context.WithValue(ctx, groupsKey{}, groups{eg, dg})
+}
+
+// GroupsFromContext returns the groups carried by ctx,
+// the default groups are returned for the ones not carried.
+func GroupsFromContext(ctx context.Context) (*EncoderGroup, *DecoderGroup) {
+	g, _ := ctx.Value(groupsKey{}).(groups)
+	if g.eg == nil {
+		g.eg = DefaultEncoderGroup()
+	}
+	if g.dg == nil {
+		g.dg = DefaultDecoderGroup()
+	}
+	return g.eg, g.dg
 }
 
 func Write(w io.Writer, order binary.ByteOrder, msg interface{}) error {
-	return defaultEG.Write(w, order, msg)
+	return DefaultEncoderGroup().Write(w, order, msg)
+}
+
+// WriteContext is like Write but uses the encoder group carried by ctx.
+func WriteContext(ctx context.Context, w io.Writer, order binary.ByteOrder, msg interface{}) error {
+	eg, _ := GroupsFromContext(ctx)
+	return eg.Write(w, order, msg)
 }
 
 func Encode(order binary.ByteOrder, msg interface{}) ([]byte, error) {
-	return defaultEG.Encode(order, msg)
+	return DefaultEncoderGroup().Encode(order, msg)
+}
+
+// EncodeContext is like Encode but uses the encoder group carried by ctx.
+func EncodeContext(ctx context.Context, order binary.ByteOrder, msg interface{}) ([]byte, error) {
+	eg, _ := GroupsFromContext(ctx)
+	return eg.Encode(order, msg)
+}
+
+func EncodeToPointer(p unsafe.Pointer, n int, order binary.ByteOrder, msg interface{}) (int, error) {
+	return DefaultEncoderGroup().EncodeToPointer(p, n, order, msg)
+}
+
+func EncodeSequence(order binary.ByteOrder, msgs ...interface{}) ([]byte, error) {
+	return DefaultEncoderGroup().EncodeSequence(order, msgs...)
 }
 
 func Read(r io.Reader, order binary.ByteOrder, msg interface{}) error {
-	return defaultDG.Read(r, order, msg)
+	return DefaultDecoderGroup().Read(r, order, msg)
+}
+
+// ReadContext is like Read but uses the decoder group carried by ctx.
+func ReadContext(ctx context.Context, r io.Reader, order binary.ByteOrder, msg interface{}) error {
+	_, dg := GroupsFromContext(ctx)
+	return dg.Read(r, order, msg)
+}
+
+func ReadAll(r io.Reader, order binary.ByteOrder, ptr interface{}) error {
+	return DefaultDecoderGroup().ReadAll(r, order, ptr)
 }
 
 func Decode(data []byte, order binary.ByteOrder, msg interface{}) error {
-	return defaultDG.Decode(data, order, msg)
+	return DefaultDecoderGroup().Decode(data, order, msg)
+}
+
+// DecodeContext is like Decode but uses the decoder group carried by ctx.
+func DecodeContext(ctx context.Context, data []byte, order binary.ByteOrder, msg interface{}) error {
+	_, dg := GroupsFromContext(ctx)
+	return dg.Decode(data, order, msg)
+}
+
+func DecodeExact(data []byte, order binary.ByteOrder, msg interface{}) error {
+	return DefaultDecoderGroup().DecodeExact(data, order, msg)
+}
+
+func DecodeAll(data []byte, order binary.ByteOrder, ptr interface{}) error {
+	return DefaultDecoderGroup().DecodeAll(data, order, ptr)
+}
+
+func DecodeFromPointer(p unsafe.Pointer, n int, order binary.ByteOrder, msg interface{}) error {
+	return DefaultDecoderGroup().DecodeFromPointer(p, n, order, msg)
+}
+
+func DecodeSequence(data []byte, order binary.ByteOrder, ptrs ...interface{}) error {
+	return DefaultDecoderGroup().DecodeSequence(data, order, ptrs...)
+}
+
+func Get(data []byte, order binary.ByteOrder, t reflect.Type, path string) (interface{}, error) {
+	return DefaultDecoderGroup().Get(data, order, t, path)
+}
+
+func Set(data []byte, order binary.ByteOrder, t reflect.Type, path string, value interface{}) error {
+	return DefaultEncoderGroup().Set(data, order, t, path, value)
+}
+
+func Dump(w io.Writer, data []byte, order binary.ByteOrder, t reflect.Type) error {
+	return DefaultDecoderGroup().Dump(w, data, order, t)
 }
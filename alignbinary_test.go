@@ -5,6 +5,16 @@ import (
 	"testing"
 	"bytes"
 	"reflect"
+	"io"
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
 )
 
 // TODO
@@ -48,6 +58,677 @@ func TestReadSlice(t *testing.T) {
 	checkResult(t, "TestReadSlice", order, err, val, goStructSlice)
 }
 
+// chunkWriter records the largest size of a single write.
+type chunkWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		w.max = len(p)
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestWriteReadLargeSlice(t *testing.T) {
+	msg := make([]Struct, 2*chunkSize/binary.Size(cgoStruct)+1)
+	for i := range msg {
+		msg[i] = goStruct
+		msg[i].Uint32 = uint32(i)
+	}
+	want, _ := Encode(order, msg)
+
+	w := &chunkWriter{}
+	err := Write(w, order, msg)
+	checkResult(t, "TestWriteReadLargeSlice", order, err, w.Bytes(), want)
+	if w.max > chunkSize {
+		t.Errorf("TestWriteReadLargeSlice: write %v bytes at once, want at most %v", w.max, chunkSize)
+	}
+
+	val := make([]Struct, len(msg))
+	err = Read(bytes.NewReader(w.Bytes()), order, val)
+	checkResult(t, "TestWriteReadLargeSlice", order, err, val, msg)
+
+	err = Read(bytes.NewReader(w.Bytes()[:len(want)-1]), order, val)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TestWriteReadLargeSlice: have error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestWriteReadLargeBasicSlice(t *testing.T) {
+	msg := make([]uint32, chunkSize)
+	for i := range msg {
+		msg[i] = uint32(i)
+	}
+	srcBuf := &bytes.Buffer{}
+	binary.Write(srcBuf, order, msg)
+
+	w := &chunkWriter{}
+	err := Write(w, order, msg)
+	checkResult(t, "TestWriteReadLargeBasicSlice", order, err, w.Bytes(), srcBuf.Bytes())
+	if w.max > chunkSize {
+		t.Errorf("TestWriteReadLargeBasicSlice: write %v bytes at once, want at most %v", w.max, chunkSize)
+	}
+
+	val := make([]uint32, len(msg))
+	err = Read(bytes.NewReader(w.Bytes()), order, val)
+	checkResult(t, "TestWriteReadLargeBasicSlice", order, err, val, msg)
+}
+
+func TestStreamEncoderDecoder(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewStreamEncoder(buf, order, nil)
+	for _, msg := range []interface{}{goStruct, uint16(7), goStructSlice} {
+		if err := enc.Encode(msg); err != nil {
+			t.Fatalf("TestStreamEncoderDecoder: %v", err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatalf("TestStreamEncoderDecoder: %v", err)
+	}
+	if enc.Offset() != int64(buf.Len()) {
+		t.Errorf("TestStreamEncoderDecoder: have offset %v, want %v", enc.Offset(), buf.Len())
+	}
+
+	dec := NewStreamDecoder(bytes.NewReader(buf.Bytes()), order, nil)
+	val := Struct{}
+	var u uint16
+	vals := make([]Struct, sliceLen)
+	err := dec.Decode(&val)
+	checkResult(t, "TestStreamEncoderDecoder", order, err, val, goStruct)
+	err = dec.Decode(&u)
+	checkResult(t, "TestStreamEncoderDecoder", order, err, u, uint16(7))
+	err = dec.Decode(vals)
+	checkResult(t, "TestStreamEncoderDecoder", order, err, vals, goStructSlice)
+	if dec.Offset() != int64(buf.Len()) {
+		t.Errorf("TestStreamEncoderDecoder: have offset %v, want %v", dec.Offset(), buf.Len())
+	}
+	if err = dec.Decode(&u); err != io.EOF {
+		t.Errorf("TestStreamEncoderDecoder: have error %v, want %v", err, io.EOF)
+	}
+}
+
+func TestStreamAlignment(t *testing.T) {
+	buf := &bytes.Buffer{}
+	enc := NewStreamEncoder(buf, order, NewEncoderGroup(Align4Byte))
+	enc.Encode(uint8(1))
+	enc.Encode(uint16(2))
+	enc.Encode(uint64(3))
+	enc.AlignTo(16)
+	enc.Encode(uint8(4))
+	enc.Flush()
+	want := []byte{1, 0, 2, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 4}
+	checkResult(t, "TestStreamAlignment", order, nil, buf.Bytes(), want)
+
+	dec := NewStreamDecoder(buf, order, NewDecoderGroup(Align4Byte))
+	var u8 uint8
+	var u16 uint16
+	var u64 uint64
+	dec.Decode(&u8)
+	dec.Decode(&u16)
+	dec.Decode(&u64)
+	dec.AlignTo(16)
+	err := dec.Decode(&u8)
+	checkResult(t, "TestStreamAlignment", order, err, []interface{}{u16, u64, u8}, []interface{}{uint16(2), uint64(3), uint8(4)})
+
+	// The truncated padding is reported as a *DecodeError, the end of the stream as io.EOF.
+	dec = NewStreamDecoder(bytes.NewReader(want[:10]), order, nil)
+	dec.Decode(&u8)
+	dec.Decode(&u16)
+	err = dec.AlignTo(16)
+	checkResult(t, "TestStreamAlignment", order, nil, err, &DecodeError{Offset: 4, Need: 12, Have: 6, Err: io.ErrUnexpectedEOF})
+	checkResult(t, "TestStreamAlignment", order, nil, dec.AlignTo(32), io.EOF)
+}
+
+type sequenceStruct struct {
+	Hdr     uint8
+	Body    [2]uint32
+	Trailer uint16
+}
+
+func TestEncodeDecodeSequence(t *testing.T) {
+	for _, af := range []AlignFactor{AlignDefault, Align1Byte, Align2Byte} {
+		eg, dg := NewEncoderGroup(af), NewDecoderGroup(af)
+		want, _ := eg.Encode(order, sequenceStruct{1, [2]uint32{2, 3}, 4})
+		data, err := eg.EncodeSequence(order, uint8(1), []uint32{2, 3}, uint16(4))
+		checkResult(t, "TestEncodeDecodeSequence", order, err, data, want)
+
+		var hdr uint8
+		var body [2]uint32
+		var trailer uint16
+		err = dg.DecodeSequence(data, order, &hdr, &body, &trailer)
+		checkResult(t, "TestEncodeDecodeSequence", order, err,
+			sequenceStruct{hdr, body, trailer}, sequenceStruct{1, [2]uint32{2, 3}, 4})
+	}
+}
+
+func TestCodec(t *testing.T) {
+	c := NewCodec(WithProfile(ProfileNetwork), WithStrict(true))
+	srcBuf := &bytes.Buffer{}
+	binary.Write(srcBuf, binary.BigEndian, goStruct)
+
+	data, err := c.Marshal(goStruct)
+	checkResult(t, "TestCodec", c.Order(), err, data, srcBuf.Bytes())
+	if c.Size(goStruct) != len(data) {
+		t.Errorf("TestCodec: have size %v, want %v", c.Size(goStruct), len(data))
+	}
+
+	val := Struct{}
+	err = c.Unmarshal(data, &val)
+	checkResult(t, "TestCodec", c.Order(), err, val, goStruct)
+	var te *TrailingDataError
+	if err = c.Unmarshal(append(data, 0), &val); !errors.As(err, &te) || te.Have-te.Size != 1 {
+		t.Errorf("TestCodec: have error %v, want a *TrailingDataError of 1 byte", err)
+	}
+}
+
+func TestScopedGroups(t *testing.T) {
+	msg := sequenceStruct{1, [2]uint32{2, 3}, 4}
+	packed, _ := NewEncoderGroup(Align1Byte).Encode(order, msg)
+	aligned, _ := Encode(order, msg)
+
+	ctx := ContextWithGroups(context.Background(), NewEncoderGroup(Align1Byte), NewDecoderGroup(Align1Byte))
+	data, err := EncodeContext(ctx, order, msg)
+	checkResult(t, "TestScopedGroups", order, err, data, packed)
+	val := sequenceStruct{}
+	err = DecodeContext(ctx, data, order, &val)
+	checkResult(t, "TestScopedGroups", order, err, val, msg)
+	data, err = Encode(order, msg)
+	checkResult(t, "TestScopedGroups", order, err, data, aligned)
+
+	// The groups of the parallel tests don't interfere with each other.
+	for _, af := range []AlignFactor{Align1Byte, Align2Byte} {
+		t.Run(fmt.Sprint(af), func(t *testing.T) {
+			t.Parallel()
+			eg, dg := NewEncoderGroup(af), NewDecoderGroup(af)
+			want, _ := eg.Encode(order, msg)
+			ctx := ContextWithGroups(context.Background(), eg, dg)
+			for i := 0; i < 1000; i++ {
+				data, err := EncodeContext(ctx, order, msg)
+				checkResult(t, "TestScopedGroups", order, err, data, want)
+				val := sequenceStruct{}
+				err = DecodeContext(ctx, data, order, &val)
+				checkResult(t, "TestScopedGroups", order, err, val, msg)
+				if t.Failed() {
+					return
+				}
+			}
+		})
+	}
+}
+
+type layoutStruct struct {
+	A   uint8
+	Seq sequenceStruct
+	B   uint16
+}
+
+func TestLayout(t *testing.T) {
+	l, err := Layout(reflect.TypeOf(layoutStruct{}), Align2Byte)
+	if err != nil {
+		t.Fatalf("TestLayout: %v", err)
+	}
+	type row struct {
+		Path                              string
+		Offset, Size, PadBefore, PadAfter uintptr
+	}
+	have := []row{}
+	for _, f := range l.Fields {
+		have = append(have, row{f.Path, f.Offset, f.Size, f.PadBefore, f.PadAfter})
+		for _, f := range f.Fields {
+			have = append(have, row{f.Path, f.Offset, f.Size, f.PadBefore, f.PadAfter})
+		}
+	}
+	want := []row{
+		{"A", 0, 1, 0, 1},
+		{"Seq", 2, 12, 1, 0},
+		{"Seq.Hdr", 2, 1, 0, 1},
+		{"Seq.Body", 4, 8, 1, 0},
+		{"Seq.Trailer", 12, 2, 0, 0},
+		{"B", 14, 2, 0, 0},
+	}
+	checkResult(t, "TestLayout", order, nil, have, want)
+	checkResult(t, "TestLayout", order, nil, []uintptr{l.Size, l.Align, l.TrailingPad}, []uintptr{16, 2, 0})
+	if s := l.String(); !strings.Contains(s, "// XXX 1 byte hole\n") {
+		t.Errorf("TestLayout: have\n%v\nwant a 1 byte hole", s)
+	}
+	l, err = Layout(reflect.TypeOf(struct {
+		A uint16
+		B uint8
+	}{}), Align2Byte)
+	if s := l.String(); err != nil || !strings.Contains(s, "// XXX 1 byte trailing padding\n") {
+		t.Errorf("TestLayout: have\n%v\n%v, want 1 byte trailing padding", s, err)
+	}
+
+	if _, err = Layout(reflect.TypeOf(struct{ S []int8 }{}), AlignDefault); err == nil {
+		t.Errorf("TestLayout: have no error for a slice field")
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	msg := layoutStruct{1, sequenceStruct{2, [2]uint32{3, 4}, 5}, 6}
+	typ := reflect.TypeOf(msg)
+	for _, af := range []AlignFactor{AlignDefault, Align1Byte, Align2Byte} {
+		eg, dg := NewEncoderGroup(af), NewDecoderGroup(af)
+		data, _ := eg.Encode(order, msg)
+		for path, want := range map[string]interface{}{
+			"A":           uint8(1),
+			"Seq.Body[1]": uint32(4),
+			"Seq.Trailer": uint16(5),
+			"Seq.Body":    [2]uint32{3, 4},
+			"Seq":         msg.Seq,
+			"B":           uint16(6),
+		} {
+			have, err := dg.Get(data, order, typ, path)
+			checkResult(t, "TestGetSet", order, err, have, want)
+		}
+
+		err := eg.Set(data, order, typ, "Seq.Body[0]", uint32(7))
+		checkResult(t, "TestGetSet", order, err, nil, nil)
+		err = eg.Set(data, order, typ, "B", uint16(8))
+		checkResult(t, "TestGetSet", order, err, nil, nil)
+		want := msg
+		want.Seq.Body[0], want.B = 7, 8
+		var val layoutStruct
+		err = dg.Decode(data, order, &val)
+		checkResult(t, "TestGetSet", order, err, val, want)
+
+		for _, path := range []string{"C", "A.B", "A[0]", "Seq.Body[2]", "Seq.Body[x]", "Seq.Body[0"} {
+			if _, err = dg.Get(data, order, typ, path); err == nil {
+				t.Errorf("TestGetSet: have no error for path %q", path)
+			}
+		}
+		if err = eg.Set(data, order, typ, "B", 8); err == nil {
+			t.Errorf("TestGetSet: have no error for a value of type int")
+		}
+		short := data[:len(data)-3]
+		wantErr := &DecodeError{Offset: int64(len(data) - 2), Need: len(data), Have: len(short),
+			FieldPath: "B", Type: typ, Err: io.ErrUnexpectedEOF}
+		if af == AlignDefault {
+			wantErr.Offset, wantErr.Need = int64(len(data)-4), len(data)-2
+		}
+		_, err = dg.Get(short, order, typ, "B")
+		checkResult(t, "TestGetSet", order, nil, err, wantErr)
+		err = eg.Set(short, order, typ, "B", uint16(8))
+		checkResult(t, "TestGetSet", order, nil, err, wantErr)
+	}
+
+	// The paths beyond maxCachedPaths are resolved without caching.
+	dg := NewDecoderGroup(Align1Byte)
+	data, _ := NewEncoderGroup(Align1Byte).Encode(order, msg)
+	for i := 0; i < maxCachedPaths+10; i++ {
+		path := "Seq.Body[" + strings.Repeat("0", i) + "1]"
+		have, err := dg.Get(data, order, typ, path)
+		checkResult(t, "TestGetSet", order, err, have, uint32(4))
+	}
+	n := 0
+	dg.paths.refs.Range(func(_, _ any) bool { n++; return true })
+	checkResult(t, "TestGetSet", order, nil, n, maxCachedPaths)
+}
+
+func TestDump(t *testing.T) {
+	dg := NewDecoderGroup(Align2Byte)
+	data, _ := NewEncoderGroup(Align2Byte).Encode(order, layoutStruct{1, sequenceStruct{2, [2]uint32{3, 4}, 5}, 6})
+	data[1] = 0xff
+	buf := &bytes.Buffer{}
+	err := dg.Dump(buf, append(data, 7), order, reflect.TypeOf(layoutStruct{}))
+	want := `// alignbinary.layoutStruct: 16 bytes, alignment factor 2, 17 bytes of data
+[0, 1)    A            01                       1
+[1, 2)    (padding)    ff                       !! non-zero padding
+[2, 3)    Seq.Hdr      02                       2
+[3, 4)    (padding)    00                       zero
+[4, 12)   Seq.Body     03 00 00 00 04 00 00 00  [3 4]
+[12, 14)  Seq.Trailer  05 00                    5
+[14, 16)  B            06 00                    6
+// 1 bytes of trailing data
+`
+	checkResult(t, "TestDump", order, err, buf.String(), want)
+
+	buf.Reset()
+	err = dg.Dump(buf, data[:9], order, reflect.TypeOf(layoutStruct{}))
+	want = `// alignbinary.layoutStruct: 16 bytes, alignment factor 2, 9 bytes of data
+[0, 1)   A          01              1
+[1, 2)   (padding)  ff              !! non-zero padding
+[2, 3)   Seq.Hdr    02              2
+[3, 4)   (padding)  00              zero
+[4, 12)  Seq.Body   03 00 00 00 04  !! truncated: need 8 bytes, have 5
+`
+	if !errors.Is(err, io.ErrUnexpectedEOF) || buf.String() != want {
+		t.Errorf("TestDump: have %v\n%v\nwant %v\n%v", err, buf, io.ErrUnexpectedEOF, want)
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	dg := NewDecoderGroup(Align2Byte)
+	data, _ := NewEncoderGroup(Align2Byte).Encode(order, []layoutStruct{{}, {}, {}})
+	check := func(name string, err error, want DecodeError) {
+		t.Helper()
+		var de *DecodeError
+		if !errors.As(err, &de) || !errors.Is(err, want.Err) {
+			t.Errorf("%v: have error %v, want a *DecodeError wrapping %v", name, err, want.Err)
+			return
+		}
+		de.Err, want.Err = nil, nil
+		checkResult(t, name, order, nil, *de, want)
+	}
+
+	err := dg.Decode(data[:9], order, &layoutStruct{})
+	check("TestDecodeError Decode", err, DecodeError{8, 16, 9, "Seq.Body[1]", reflect.TypeOf(layoutStruct{}), io.ErrUnexpectedEOF})
+	err = dg.Decode(data[:40], order, make([]layoutStruct, 3))
+	check("TestDecodeError Decode slice", err, DecodeError{40, 48, 40, "[2].Seq.Body[1]", reflect.TypeOf([]layoutStruct{}), io.ErrUnexpectedEOF})
+	err = dg.Decode(data[:3], order, new(uint32))
+	check("TestDecodeError Decode basic", err, DecodeError{0, 4, 3, "", reflect.TypeOf(uint32(0)), io.ErrUnexpectedEOF})
+
+	err = dg.Read(bytes.NewReader(data[:21]), order, make([]layoutStruct, 3))
+	check("TestDecodeError Read slice", err, DecodeError{20, 48, 21, "[1].Seq.Body[0]", reflect.TypeOf([]layoutStruct{}), io.ErrUnexpectedEOF})
+	readErr := errors.New("connection reset")
+	err = dg.Read(io.MultiReader(bytes.NewReader(data[:6]), iotestErrReader{readErr}), order, &layoutStruct{})
+	check("TestDecodeError Read error", err, DecodeError{4, 16, 6, "Seq.Body[0]", reflect.TypeOf(layoutStruct{}), readErr})
+	if err = dg.Read(bytes.NewReader(nil), order, &layoutStruct{}); err != io.EOF {
+		t.Errorf("TestDecodeError: have error %v, want %v", err, io.EOF)
+	}
+
+	var hdr uint8
+	var seq sequenceStruct
+	err = dg.DecodeSequence(data[:7], order, &hdr, &seq)
+	check("TestDecodeError DecodeSequence", err, DecodeError{4, 14, 7, "Body[0]", reflect.TypeOf(seq), io.ErrUnexpectedEOF})
+}
+
+func TestDecodeExactAll(t *testing.T) {
+	msg := sequenceStruct{1, [2]uint32{2, 3}, 4}
+	data, _ := NewEncoderGroup(Align1Byte).Encode(order, []sequenceStruct{msg, msg, msg})
+	dg := NewDecoderGroup(Align1Byte)
+
+	var val sequenceStruct
+	err := dg.DecodeExact(data[:11], order, &val)
+	checkResult(t, "TestDecodeExactAll", order, err, val, msg)
+	var te *TrailingDataError
+	if err = dg.DecodeExact(data[:12], order, &val); !errors.As(err, &te) || *te != (TrailingDataError{11, 12, reflect.TypeOf(val)}) {
+		t.Errorf("TestDecodeExactAll: have error %v, want a *TrailingDataError", err)
+	}
+	if err = dg.DecodeExact(data[:10], order, &val); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TestDecodeExactAll: have error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	if err = NewDecoderGroup(Align1Byte, WithExactLength(true)).Decode(data[:12], order, new(uint8)); !errors.As(err, &te) {
+		t.Errorf("TestDecodeExactAll: have error %v, want a *TrailingDataError", err)
+	}
+	if err = NewDecoderGroup(Align1Byte, WithExactLength(true)).DecodeSequence(data[:2], order); !errors.As(err, &te) || *te != (TrailingDataError{0, 2, nil}) {
+		t.Errorf("TestDecodeExactAll: have error %v, want a *TrailingDataError without type", err)
+	}
+	if err = dg.Decode(data[:12], order, &val); err != nil {
+		t.Errorf("TestDecodeExactAll: have error %v, want nil", err)
+	}
+
+	vals := make([]sequenceStruct, 5)
+	err = dg.DecodeAll(data, order, &vals)
+	checkResult(t, "TestDecodeExactAll", order, err, vals, []sequenceStruct{msg, msg, msg})
+	err = dg.DecodeAll(data[:30], order, &vals)
+	checkResult(t, "TestDecodeExactAll", order, nil, vals, []sequenceStruct{msg, msg})
+	var de *DecodeError
+	if !errors.As(err, &de) || de.FieldPath != "[2].Body[1]" || de.Offset != 27 {
+		t.Errorf("TestDecodeExactAll: have error %v, want a *DecodeError at [2].Body[1]", err)
+	}
+}
+
+func TestReadAll(t *testing.T) {
+	msgs := make([]sequenceStruct, 10000)
+	for i := range msgs {
+		msgs[i] = sequenceStruct{uint8(i), [2]uint32{uint32(i), 2}, 3}
+	}
+	data, _ := NewEncoderGroup(Align1Byte).Encode(order, msgs)
+	dg := NewDecoderGroup(Align1Byte)
+
+	vals := make([]sequenceStruct, 3, 5)
+	err := dg.ReadAll(bytes.NewReader(data), order, &vals)
+	checkResult(t, "TestReadAll", order, err, vals, msgs)
+
+	err = dg.ReadAll(bytes.NewReader(nil), order, &vals)
+	checkResult(t, "TestReadAll", order, err, vals, []sequenceStruct{})
+
+	var de *DecodeError
+	err = dg.ReadAll(bytes.NewReader(data[:11*3+5]), order, &vals)
+	checkResult(t, "TestReadAll", order, nil, vals, msgs[:3])
+	if !errors.As(err, &de) || !errors.Is(err, io.ErrUnexpectedEOF) || de.FieldPath != "[3].Body[1]" || de.Have != 38 {
+		t.Errorf("TestReadAll: have error %v, want a *DecodeError at [3].Body[1]", err)
+	}
+
+	limited := NewDecoderGroup(Align1Byte, WithMaxRecords(3))
+	err = limited.ReadAll(bytes.NewReader(data[:11*3]), order, &vals)
+	checkResult(t, "TestReadAll", order, err, vals, msgs[:3])
+	if err = limited.ReadAll(bytes.NewReader(data[:11*3+1]), order, &vals); !errors.As(err, &de) {
+		t.Errorf("TestReadAll: have error %v, want a *DecodeError of the partial element", err)
+	}
+	if err = limited.ReadAll(bytes.NewReader(data), order, &vals); err != ErrTooManyRecords {
+		t.Errorf("TestReadAll: have error %v, want %v", err, ErrTooManyRecords)
+	}
+	checkResult(t, "TestReadAll", order, nil, vals, msgs[:3])
+}
+
+func TestRecords(t *testing.T) {
+	msgs := make([]sequenceStruct, 10000)
+	for i := range msgs {
+		msgs[i] = sequenceStruct{uint8(i), [2]uint32{uint32(i), 2}, 3}
+	}
+	data, _ := NewEncoderGroup(Align1Byte).Encode(order, msgs)
+	dg := NewDecoderGroup(Align1Byte)
+
+	for name, records := range map[string]func(r io.Reader) iter.Seq2[*sequenceStruct, error]{
+		"Records": func(r io.Reader) iter.Seq2[*sequenceStruct, error] {
+			return Records[sequenceStruct](r, order, dg)
+		},
+		"BufferedRecords": func(r io.Reader) iter.Seq2[*sequenceStruct, error] {
+			return BufferedRecords[sequenceStruct](r, order, dg)
+		},
+	} {
+		var vals []sequenceStruct
+		for rec, err := range records(bytes.NewReader(data)) {
+			if err != nil {
+				t.Fatalf("TestRecords %v: have error %v", name, err)
+			}
+			vals = append(vals, *rec)
+		}
+		checkResult(t, "TestRecords "+name, order, nil, vals, msgs)
+
+		vals = vals[:0]
+		var de *DecodeError
+		for rec, err := range records(bytes.NewReader(data[:11*3+5])) {
+			if err != nil {
+				if !errors.As(err, &de) || !errors.Is(err, io.ErrUnexpectedEOF) || de.FieldPath != "[3].Body[1]" {
+					t.Errorf("TestRecords %v: have error %v, want a *DecodeError at [3].Body[1]", name, err)
+				}
+				break
+			}
+			vals = append(vals, *rec)
+		}
+		checkResult(t, "TestRecords "+name, order, nil, vals, msgs[:3])
+
+		var n int
+		for range records(bytes.NewReader(data)) {
+			if n++; n == 2 {
+				break
+			}
+		}
+		if n != 2 {
+			t.Errorf("TestRecords %v: have %v iterations after break, want 2", name, n)
+		}
+	}
+}
+
+func TestRecordFile(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "records"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString("HDR\x00")
+
+	c := NewCodec(WithAlign(Align2Byte), WithOrder(order))
+	rf, err := NewRecordFile[sequenceStruct](f, 4, c, WithHeader(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make([]sequenceStruct, 10000)
+	for i := range msgs {
+		msgs[i] = sequenceStruct{uint8(i), [2]uint32{uint32(i), 2}, 3}
+	}
+	i, err := rf.Append(msgs[:1]...)
+	checkResult(t, "TestRecordFile", order, err, i, 0)
+	i, err = rf.Append(msgs[1:]...)
+	checkResult(t, "TestRecordFile", order, err, i, 1)
+	checkResult(t, "TestRecordFile", order, nil, rf.Size(), int64(4+12*len(msgs)))
+
+	// Reopen the file and read the records concurrently.
+	rf, err = NewRecordFile[sequenceStruct](f, 4+12*int64(len(msgs)), c, WithHeader(4))
+	checkResult(t, "TestRecordFile", order, err, rf.Len(), len(msgs))
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			var rec sequenceStruct
+			for i := g; i < len(msgs); i += 97 {
+				if err := rf.ReadAt(i, &rec); err != nil || rec != msgs[i] {
+					t.Errorf("TestRecordFile: have %v, %v at %v, want %v", rec, err, i, msgs[i])
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	vals := make([]sequenceStruct, len(msgs))
+	err = rf.ReadRange(0, len(msgs), vals)
+	checkResult(t, "TestRecordFile", order, err, vals, msgs)
+
+	err = rf.WriteAt(5, &msgs[0])
+	checkResult(t, "TestRecordFile", order, err, nil, nil)
+	var rec sequenceStruct
+	err = rf.ReadAt(5, &rec)
+	checkResult(t, "TestRecordFile", order, err, rec, msgs[0])
+
+	hdr, _ := io.ReadAll(rf.Header())
+	checkResult(t, "TestRecordFile", order, nil, string(hdr), "HDR\x00")
+	if err = rf.ReadAt(len(msgs), &rec); err == nil {
+		t.Errorf("TestRecordFile: have no error for an index out of range")
+	}
+	if _, err = NewRecordFile[sequenceStruct](f, 4+12*3+1, c, WithHeader(4)); err == nil {
+		t.Errorf("TestRecordFile: have no error for a partial record")
+	}
+
+	// A storage shorter than its size reports the missing record.
+	rf, _ = NewRecordFile[sequenceStruct](bytes.NewReader(make([]byte, 4+12*2+5)), 4+12*3, c, WithHeader(4))
+	var de *DecodeError
+	if err = rf.ReadRange(0, 3, vals); !errors.As(err, &de) || de.FieldPath != "[2].Body[0]" {
+		t.Errorf("TestRecordFile: have error %v, want a *DecodeError at [2].Body[0]", err)
+	}
+	if _, err = rf.Append(msgs[0]); err == nil {
+		t.Errorf("TestRecordFile: have no error for appending to a read-only storage")
+	}
+}
+
+func TestView(t *testing.T) {
+	c := NewCodec(WithProfile(ProfileNative))
+	msgs := []sequenceStruct{{1, [2]uint32{2, 3}, 4}, {5, [2]uint32{6, 7}, 8}}
+	data, _ := c.Marshal(msgs)
+	buf := AlignedBytes[sequenceStruct](len(data))
+	copy(buf, data)
+
+	v, err := View[sequenceStruct](buf)
+	checkResult(t, "TestView", order, err, *v, msgs[0])
+	v.Trailer = 9
+	var msg sequenceStruct
+	err = c.Unmarshal(buf[:16], &msg)
+	checkResult(t, "TestView", order, err, msg.Trailer, uint16(9))
+
+	vals, err := ViewSlice[sequenceStruct](buf)
+	checkResult(t, "TestView", order, err, vals[1], msgs[1])
+	checkResult(t, "TestView", order, nil, &vals[0], v)
+
+	var de *DecodeError
+	if _, err = View[sequenceStruct](buf[:9]); !errors.As(err, &de) || de.FieldPath != "Body[1]" {
+		t.Errorf("TestView: have error %v, want a *DecodeError at Body[1]", err)
+	}
+	var te *TrailingDataError
+	if _, err = ViewSlice[sequenceStruct](buf[:20]); !errors.As(err, &te) || te.Size != 16 {
+		t.Errorf("TestView: have error %v, want a *TrailingDataError", err)
+	}
+	if _, err = View[sequenceStruct](buf[1:]); err == nil {
+		t.Errorf("TestView: have no error for unaligned data")
+	}
+	if _, err = View[struct{ A, B bool }](buf); err == nil {
+		t.Errorf("TestView: have no error for a layout with bools")
+	}
+	if _, err = View[[]uint32](buf); err == nil {
+		t.Errorf("TestView: have no error for an invalid type")
+	}
+}
+
+func TestPointer(t *testing.T) {
+	c := NewCodec(WithAlign(Align2Byte), WithOrder(order))
+	msg := layoutStruct{1, sequenceStruct{2, [2]uint32{3, 4}, 5}, 6}
+	want, _ := c.Marshal(msg)
+	// The memory is not zeroed, as the one allocated by C.
+	mem := bytes.Repeat([]byte{0xff}, 64)
+	p := unsafe.Pointer(&mem[0])
+	n, err := c.EncodeToPointer(p, len(mem), &msg)
+	checkResult(t, "TestPointer", order, err, mem[:n], want)
+
+	var have layoutStruct
+	err = c.DecodeFromPointer(p, n, &have)
+	checkResult(t, "TestPointer", order, err, have, msg)
+
+	n, err = EncodeToPointer(p, len(mem), order, []uint16{1, 2})
+	checkResult(t, "TestPointer", order, err, mem[:n], []byte{1, 0, 2, 0})
+
+	if _, err = c.EncodeToPointer(p, len(want)-1, &msg); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("TestPointer: have %v, want %v", err, io.ErrShortBuffer)
+	}
+	var de *DecodeError
+	if err = c.DecodeFromPointer(p, 5, &have); !errors.As(err, &de) || de.FieldPath != "Seq.Body[0]" {
+		t.Errorf("TestPointer: have error %v, want a *DecodeError at Seq.Body[0]", err)
+	}
+	if _, err = c.EncodeToPointer(nil, 8, &msg); err == nil {
+		t.Errorf("TestPointer: have no error for a nil pointer")
+	}
+}
+
+func TestParallel(t *testing.T) {
+	// 12 bytes each, large enough for 3 goroutines.
+	msgs := make([]sequenceStruct, 3*parallelThreshold/12+5)
+	for i := range msgs {
+		msgs[i] = sequenceStruct{uint8(i), [2]uint32{uint32(i), uint32(3 * i)}, uint16(i >> 3)}
+	}
+	want, _ := NewEncoderGroup(Align2Byte).Encode(order, msgs)
+	c := NewCodec(WithAlign(Align2Byte), WithOrder(order), WithParallelism(8))
+	have, err := c.Marshal(msgs)
+	if err != nil || !bytes.Equal(have, want) {
+		t.Fatalf("TestParallel: have %v bytes, %v, want %v bytes identical to the sequential ones", len(have), err, len(want))
+	}
+
+	vals := make([]sequenceStruct, len(msgs))
+	err = c.Unmarshal(have, vals)
+	if err != nil || !reflect.DeepEqual(vals, msgs) {
+		t.Errorf("TestParallel: have different elements, %v", err)
+	}
+	var all []sequenceStruct
+	err = NewDecoderGroup(Align2Byte, WithDecodeParallelism(3)).DecodeAll(have, order, &all)
+	if err != nil || !reflect.DeepEqual(all, msgs) {
+		t.Errorf("TestParallel: have different elements of DecodeAll, %v", err)
+	}
+	checkResult(t, "TestParallel", order, nil, parallelWorkers(len(have), 8), 3)
+	checkResult(t, "TestParallel", order, nil, parallelWorkers(parallelThreshold-1, 8), 0)
+}
+
+// iotestErrReader returns the err on every read.
+type iotestErrReader struct {
+	err error
+}
+
+func (r iotestErrReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
 //=========================================== Benchmark =======================
 
 func BenchmarkBinaryWrite(b *testing.B) {
@@ -105,3 +786,16 @@ func BenchmarkRead(b *testing.B) {
 		b.Fatalf("struct doesn't match:\ngot  %v;\nwant %v", val, goStruct)
 	}
 }
+
+func BenchmarkEncodeParallel(b *testing.B) {
+	msgs := make([]sequenceStruct, 1<<20)
+	for _, parallelism := range []int{1, 4} {
+		c := NewCodec(WithOrder(order), WithParallelism(parallelism))
+		b.Run(fmt.Sprint(parallelism), func(b *testing.B) {
+			b.SetBytes(int64(c.Size(msgs)))
+			for i := 0; i < b.N; i++ {
+				c.Marshal(msgs)
+			}
+		})
+	}
+}
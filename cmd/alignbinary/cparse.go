@@ -0,0 +1,706 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// cKind is the kind of a C type.
+type cKind int
+
+const (
+	cBasic cKind = iota
+	cArray
+	cStruct
+	cUnion
+)
+
+// cType is a C type with its layout.
+type cType struct {
+	kind cKind
+	// name is the Go type name of a basic type, or the tag or typedef name
+	// of a struct or union, which is empty if anonymous.
+	name  string
+	size  int
+	align int
+	// elem and len describe an array.
+	elem *cType
+	len  int
+	// fields are the members of a struct or union.
+	fields []*cField
+}
+
+// cField is a member of a C struct or union.
+type cField struct {
+	name   string
+	typ    *cType
+	offset int
+}
+
+// cBasicSpecs maps the C basic type specifiers, sorted and joined by spaces,
+// to the Go types. The sizes follow the LP64 data model.
+var cBasicSpecs = map[string]string{
+	"_Bool":                  "bool",
+	"bool":                   "bool",
+	"char":                   "int8",
+	"char signed":            "int8",
+	"char unsigned":          "uint8",
+	"short":                  "int16",
+	"int short":              "int16",
+	"short signed":           "int16",
+	"int short signed":       "int16",
+	"short unsigned":         "uint16",
+	"int short unsigned":     "uint16",
+	"int":                    "int32",
+	"signed":                 "int32",
+	"int signed":             "int32",
+	"unsigned":               "uint32",
+	"int unsigned":           "uint32",
+	"long":                   "int64",
+	"int long":               "int64",
+	"long signed":            "int64",
+	"long unsigned":          "uint64",
+	"int long unsigned":      "uint64",
+	"long long":              "int64",
+	"int long long":          "int64",
+	"long long signed":       "int64",
+	"long long unsigned":     "uint64",
+	"int long long unsigned": "uint64",
+	"float":                  "float32",
+	"double":                 "float64",
+	"int8_t":                 "int8",
+	"uint8_t":                "uint8",
+	"int16_t":                "int16",
+	"uint16_t":               "uint16",
+	"int32_t":                "int32",
+	"uint32_t":               "uint32",
+	"int64_t":                "int64",
+	"uint64_t":               "uint64",
+}
+
+// goBasicSizes are the sizes of the Go basic types.
+var goBasicSizes = map[string]int{
+	"bool": 1, "int8": 1, "uint8": 1, "int16": 2, "uint16": 2,
+	"int32": 4, "uint32": 4, "int64": 8, "uint64": 8, "float32": 4, "float64": 8,
+}
+
+// cHeader is the result of parsing a C header.
+type cHeader struct {
+	// consts are the integer constants defined by #define and the enumerators, in order.
+	consts []cConst
+	// types are the named structs and unions, in order of definition.
+	types []*cType
+	// typedefs are the typedef names of all types.
+	typedefs map[string]*cType
+	// tags are the tagged structs and unions.
+	tags map[string]*cType
+}
+
+type cConst struct {
+	name  string
+	value int64
+}
+
+// cToken is a token of the C source.
+type cToken struct {
+	text string
+	line int
+	// directive is true if the token is a whole preprocessing directive line.
+	directive bool
+}
+
+// cParser parses a practical subset of C headers: #define of integer constants,
+// #pragma pack, typedefs, structs, unions, enums and fixed-size arrays.
+// The declarations of functions and variables are skipped, as are the
+// extern "C" blocks of the guards for C++.
+type cParser struct {
+	h      *cHeader
+	toks   []cToken
+	pos    int
+	consts map[string]int64
+	// pack is the current maximum alignment set by #pragma pack, 0 for none.
+	pack      int
+	packStack []int
+	// linkage is the number of the open extern "C" blocks.
+	linkage int
+}
+
+var (
+	blockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	lineComment  = regexp.MustCompile(`//[^\n]*`)
+	cTokenRE     = regexp.MustCompile(`"(?:[^"\\]|\\.)*"|[A-Za-z_][A-Za-z0-9_]*|0[xX][0-9A-Fa-f]+[uUlL]*|[0-9]+[uUlL]*|<<|>>|\S`)
+)
+
+// parseC parses the C header source.
+func parseC(src string) (*cHeader, error) {
+	// Remove the comments but keep the line numbers.
+	src = blockComment.ReplaceAllStringFunc(src, func(s string) string {
+		return strings.Repeat("\n", strings.Count(s, "\n"))
+	})
+	src = lineComment.ReplaceAllString(src, "")
+	src = strings.ReplaceAll(src, "\\\n", " ")
+	p := &cParser{
+		h:      &cHeader{typedefs: make(map[string]*cType), tags: make(map[string]*cType)},
+		consts: make(map[string]int64),
+	}
+	for i, line := range strings.Split(src, "\n") {
+		if s := strings.TrimSpace(line); strings.HasPrefix(s, "#") {
+			p.toks = append(p.toks, cToken{text: s, line: i + 1, directive: true})
+			continue
+		}
+		for _, text := range cTokenRE.FindAllString(line, -1) {
+			p.toks = append(p.toks, cToken{text: text, line: i + 1})
+		}
+	}
+	for p.pos < len(p.toks) {
+		var err error
+		if p.toks[p.pos].directive {
+			err = p.directive(p.next().text)
+		} else {
+			err = p.declaration()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", p.line(), err)
+		}
+	}
+	return p.h, nil
+}
+
+// line returns the line number of the current token.
+func (p *cParser) line() int {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos].line
+	}
+	return p.toks[len(p.toks)-1].line
+}
+
+func (p *cParser) peek() string {
+	if p.pos < len(p.toks) && !p.toks[p.pos].directive {
+		return p.toks[p.pos].text
+	}
+	return ""
+}
+
+func (p *cParser) next() cToken {
+	if p.pos >= len(p.toks) {
+		return cToken{}
+	}
+	p.pos++
+	return p.toks[p.pos-1]
+}
+
+func (p *cParser) expect(text string) error {
+	if tok := p.peek(); tok != text {
+		return fmt.Errorf("expected %q, found %q", text, tok)
+	}
+	p.next()
+	return nil
+}
+
+var (
+	defineRE = regexp.MustCompile(`^#\s*define\s+([A-Za-z_][A-Za-z0-9_]*)(\s+(.*))?$`)
+	packRE   = regexp.MustCompile(`^#\s*pragma\s+pack\s*\(\s*(push|pop)?\s*,?\s*([0-9]*)\s*\)`)
+)
+
+// directive handles a preprocessing directive line.
+// The directives other than #define and #pragma pack are ignored.
+func (p *cParser) directive(line string) error {
+	if m := defineRE.FindStringSubmatch(line); m != nil {
+		// Only the integer constants are kept, the others are ignored.
+		if m[3] == "" {
+			return nil
+		}
+		sub := &cParser{consts: p.consts}
+		for _, text := range cTokenRE.FindAllString(m[3], -1) {
+			sub.toks = append(sub.toks, cToken{text: text})
+		}
+		if v, err := sub.expr(); err == nil && sub.pos == len(sub.toks) {
+			p.consts[m[1]] = v
+			p.h.consts = append(p.h.consts, cConst{m[1], v})
+		}
+		return nil
+	}
+	if m := packRE.FindStringSubmatch(line); m != nil {
+		n := 0
+		if m[2] != "" {
+			n, _ = strconv.Atoi(m[2])
+			if n > 8 || n&(n-1) != 0 {
+				return fmt.Errorf("invalid pack %v", n)
+			}
+		}
+		switch m[1] {
+		case "push":
+			p.packStack = append(p.packStack, p.pack)
+			if m[2] != "" {
+				p.pack = n
+			}
+		case "pop":
+			if len(p.packStack) == 0 {
+				return fmt.Errorf("#pragma pack(pop) without push")
+			}
+			p.pack = p.packStack[len(p.packStack)-1]
+			p.packStack = p.packStack[:len(p.packStack)-1]
+		default:
+			p.pack = n
+		}
+	}
+	return nil
+}
+
+// declaration parses a typedef, or a struct, union or enum declaration, ended by a semicolon.
+// The other declarations are skipped, and so are the typedefs of pointers and functions.
+func (p *cParser) declaration() error {
+	switch p.peek() {
+	case "extern":
+		p.next()
+		if strings.HasPrefix(p.peek(), `"`) {
+			// A linkage specification, e.g. extern "C" {.
+			p.next()
+			if p.peek() == "{" {
+				p.next()
+				p.linkage++
+				return nil
+			}
+		}
+		return p.skip()
+	case "}":
+		if p.linkage > 0 {
+			p.next()
+			p.linkage--
+			return nil
+		}
+	}
+	typedef := p.peek() == "typedef"
+	if typedef {
+		p.next()
+		if p.typedefsPointer() {
+			return p.skip()
+		}
+	} else if !p.definesType() {
+		return p.skip()
+	}
+	base, err := p.typeSpec()
+	if err != nil {
+		return err
+	}
+	if p.peek() == ";" {
+		p.next()
+		if typedef {
+			return fmt.Errorf("typedef without name")
+		}
+		return nil
+	}
+	if !typedef {
+		// The variables declared with the type.
+		return p.skip()
+	}
+	for {
+		if tok := p.peek(); tok == "*" || tok == "(" {
+			return p.skip()
+		}
+		name, t, err := p.declarator(base)
+		if err != nil {
+			return err
+		}
+		if p.peek() == "(" {
+			// A function type.
+			return p.skip()
+		}
+		p.h.typedefs[name] = t
+		switch {
+		case base.kind != cStruct && base.kind != cUnion:
+		case base.name == "":
+			// Name an anonymous struct or union by its first typedef.
+			base.name = name
+			if t != base {
+				base.name += "Elem"
+			}
+			p.h.types = append(p.h.types, base)
+		case t == base && t.name != name:
+			// An alias of a tagged struct or union.
+			p.h.types = append(p.h.types, &cType{kind: t.kind, name: name, elem: t})
+		}
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	return p.expect(";")
+}
+
+// definesType reports whether the declaration at the current token defines
+// a struct, union or enum.
+func (p *cParser) definesType() bool {
+	for i := p.pos; i < len(p.toks) && !p.toks[i].directive; i++ {
+		switch p.toks[i].text {
+		case "const", "volatile":
+		case "struct", "union", "enum":
+			if i++; i < len(p.toks) && isIdent(p.toks[i].text) {
+				i++
+			}
+			return i < len(p.toks) && p.toks[i].text == "{"
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// typedefsPointer reports whether the typedef at the current token declares
+// a pointer or function type without defining a struct, union or enum,
+// e.g. of an opaque struct or a callback.
+func (p *cParser) typedefsPointer() bool {
+	// dims is the depth of the array dimensions, whose expressions are skipped.
+	var dims int
+	for i := p.pos; i < len(p.toks) && !p.toks[i].directive; i++ {
+		switch p.toks[i].text {
+		case "[":
+			dims++
+		case "]":
+			dims--
+		case "*", "(":
+			if dims == 0 {
+				return true
+			}
+		case "{", ";":
+			return false
+		}
+	}
+	return false
+}
+
+// skip skips a declaration to its semicolon, or a function definition to
+// the end of its body. The directives within are handled as usual.
+func (p *cParser) skip() error {
+	var depth int
+	var prev string
+	body := false
+	for p.pos < len(p.toks) {
+		if p.toks[p.pos].directive {
+			if err := p.directive(p.next().text); err != nil {
+				return err
+			}
+			continue
+		}
+		tok := p.next().text
+		switch tok {
+		case "{":
+			if depth == 0 {
+				body = prev == ")"
+			}
+			depth++
+		case "(", "[":
+			depth++
+		case "}":
+			if depth--; depth == 0 && body {
+				return nil
+			}
+		case ")", "]":
+			depth--
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+		prev = tok
+	}
+	return fmt.Errorf("unterminated declaration")
+}
+
+// typeSpec parses the type specifiers.
+func (p *cParser) typeSpec() (*cType, error) {
+	var specs []string
+	for {
+		switch tok := p.peek(); tok {
+		case "const", "volatile":
+			p.next()
+		case "struct", "union":
+			if len(specs) > 0 {
+				return nil, fmt.Errorf("unexpected %q", tok)
+			}
+			p.next()
+			return p.record(tok)
+		case "enum":
+			if len(specs) > 0 {
+				return nil, fmt.Errorf("unexpected %q", tok)
+			}
+			p.next()
+			return p.enum()
+		case "signed", "unsigned", "char", "short", "int", "long", "float", "double", "_Bool", "bool":
+			specs = append(specs, p.next().text)
+		default:
+			if len(specs) > 0 {
+				return p.basic(specs)
+			}
+			if t, ok := p.h.typedefs[tok]; ok {
+				p.next()
+				return t, nil
+			}
+			if _, ok := cBasicSpecs[tok]; ok {
+				p.next()
+				return p.basic([]string{tok})
+			}
+			return nil, fmt.Errorf("unknown type %q", tok)
+		}
+	}
+}
+
+func (p *cParser) basic(specs []string) (*cType, error) {
+	sort.Strings(specs)
+	name, ok := cBasicSpecs[strings.Join(specs, " ")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %q", strings.Join(specs, " "))
+	}
+	size := goBasicSizes[name]
+	return &cType{kind: cBasic, name: name, size: size, align: size}, nil
+}
+
+// record parses a struct or union after the keyword, which is either
+// a reference to a tagged one or a definition.
+func (p *cParser) record(keyword string) (*cType, error) {
+	kind := cStruct
+	if keyword == "union" {
+		kind = cUnion
+	}
+	var tag string
+	if tok := p.peek(); tok != "{" {
+		tag = p.next().text
+		if p.peek() != "{" {
+			t, ok := p.h.tags[tag]
+			if !ok {
+				return nil, fmt.Errorf("undefined %v %v", keyword, tag)
+			}
+			return t, nil
+		}
+	}
+	p.next()
+	t := &cType{kind: kind, name: tag}
+	for p.peek() != "}" {
+		if p.peek() == "" {
+			return nil, fmt.Errorf("unterminated %v", keyword)
+		}
+		base, err := p.typeSpec()
+		if err != nil {
+			return nil, err
+		}
+		for {
+			name, ft, err := p.declarator(base)
+			if err != nil {
+				return nil, err
+			}
+			t.fields = append(t.fields, &cField{name: name, typ: ft})
+			if p.peek() != "," {
+				break
+			}
+			p.next()
+		}
+		if err = p.expect(";"); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	if len(t.fields) == 0 {
+		return nil, fmt.Errorf("empty %v", keyword)
+	}
+	p.layout(t)
+	if tag != "" {
+		p.h.tags[tag] = t
+		p.h.types = append(p.h.types, t)
+	}
+	return t, nil
+}
+
+// enum parses an enum after the keyword, which is either a reference to
+// a tagged one or a definition. The enums have the type int, and
+// the enumerators are defined as constants.
+func (p *cParser) enum() (*cType, error) {
+	if isIdent(p.peek()) {
+		p.next()
+	}
+	if p.peek() != "{" {
+		return p.basic([]string{"int"})
+	}
+	p.next()
+	var v int64
+	for p.peek() != "}" {
+		name := p.peek()
+		if !isIdent(name) {
+			return nil, fmt.Errorf("expected enumerator, found %q", name)
+		}
+		p.next()
+		if p.peek() == "=" {
+			p.next()
+			var err error
+			if v, err = p.expr(); err != nil {
+				return nil, err
+			}
+		}
+		p.consts[name] = v
+		p.h.consts = append(p.h.consts, cConst{name, v})
+		v++
+		if p.peek() != "," {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return p.basic([]string{"int"})
+}
+
+// layout calculates the offsets of the fields, the size and alignment of t
+// with the current pack.
+func (p *cParser) layout(t *cType) {
+	t.align = 1
+	for _, f := range t.fields {
+		a := f.typ.align
+		if p.pack > 0 && a > p.pack {
+			a = p.pack
+		}
+		if a > t.align {
+			t.align = a
+		}
+		if t.kind == cUnion {
+			if f.typ.size > t.size {
+				t.size = f.typ.size
+			}
+			continue
+		}
+		f.offset = (t.size + a - 1) / a * a
+		t.size = f.offset + f.typ.size
+	}
+	t.size = (t.size + t.align - 1) / t.align * t.align
+}
+
+// declarator parses a name with optional array dimensions.
+func (p *cParser) declarator(base *cType) (string, *cType, error) {
+	switch tok := p.peek(); {
+	case tok == "*":
+		return "", nil, fmt.Errorf("unsupported pointer")
+	case !isIdent(tok):
+		return "", nil, fmt.Errorf("expected name, found %q", tok)
+	}
+	name := p.next().text
+	var dims []int
+	for p.peek() == "[" {
+		p.next()
+		if p.peek() == "]" {
+			return "", nil, fmt.Errorf("unsupported flexible array %v", name)
+		}
+		n, err := p.expr()
+		if err != nil {
+			return "", nil, err
+		}
+		if n < 0 {
+			return "", nil, fmt.Errorf("negative array length of %v", name)
+		}
+		dims = append(dims, int(n))
+		if err = p.expect("]"); err != nil {
+			return "", nil, err
+		}
+	}
+	if p.peek() == ":" {
+		return "", nil, fmt.Errorf("unsupported bit-field %v", name)
+	}
+	t := base
+	for i := len(dims) - 1; i >= 0; i-- {
+		t = &cType{kind: cArray, elem: t, len: dims[i], size: dims[i] * t.size, align: t.align}
+	}
+	return name, t, nil
+}
+
+// expr evaluates an integer constant expression of the operators
+// | & << >> + - * / % and the unary -, with the C precedences.
+func (p *cParser) expr() (int64, error) {
+	return p.binary(0)
+}
+
+var cPrecedences = map[string]int{
+	"|": 1, "&": 2, "<<": 3, ">>": 3, "+": 4, "-": 4, "*": 5, "/": 5, "%": 5,
+}
+
+func (p *cParser) binary(minPrec int) (int64, error) {
+	x, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := p.peek()
+		prec, ok := cPrecedences[op]
+		if !ok || prec <= minPrec {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(prec)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "|":
+			x |= y
+		case "&":
+			x &= y
+		case "<<":
+			x <<= uint(y)
+		case ">>":
+			x >>= uint(y)
+		case "+":
+			x += y
+		case "-":
+			x -= y
+		case "*":
+			x *= y
+		case "/", "%":
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				x /= y
+			} else {
+				x %= y
+			}
+		}
+	}
+}
+
+func (p *cParser) unary() (int64, error) {
+	switch tok := p.peek(); {
+	case tok == "-":
+		p.next()
+		x, err := p.unary()
+		return -x, err
+	case tok == "(":
+		p.next()
+		x, err := p.expr()
+		if err != nil {
+			return 0, err
+		}
+		return x, p.expect(")")
+	case isIdent(tok):
+		v, ok := p.consts[tok]
+		if !ok {
+			return 0, fmt.Errorf("undefined constant %q", tok)
+		}
+		p.next()
+		return v, nil
+	default:
+		v, err := strconv.ParseInt(strings.TrimRight(tok, "uUlL"), 0, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid constant %q", tok)
+		}
+		p.next()
+		return v, nil
+	}
+}
+
+func isIdent(s string) bool {
+	if s == "" {
+		return false
+	}
+	c := s[0]
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
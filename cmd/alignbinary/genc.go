@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/types"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+const genCUsage = "gen-c [-align n] [-o file] [-guard name] dir [TypeName...]"
+
+// runGenC generates a C header declaring the struct types in a package
+// with the same layouts as the package alignbinary encodes them.
+func runGenC(w io.Writer, args []string) error {
+	fs := newFlagSet("gen-c", genCUsage)
+	af := fs.Uint("align", alignbinary.AlignDefault, "alignment factor: 0 (system default), 1, 2, 4 or 8")
+	out := fs.String("o", "", "output file, the standard output by default")
+	guard := fs.String("guard", "", "include guard, derived from the package name by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := checkAlign(*af); err != nil {
+		return err
+	}
+	pkg, err := loadPackage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	objs, err := lookupStructs(pkg, fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	g := &cGen{af: alignbinary.AlignFactor(*af), done: make(map[*types.TypeName]bool)}
+	for _, obj := range objs {
+		if err = g.genType(obj); err != nil {
+			return err
+		}
+	}
+	if *guard == "" {
+		*guard = strings.ToUpper(pkg.Name()) + "_H"
+	}
+	src := g.header(*guard, strings.Join(append([]string{"alignbinary", "gen-c"}, args...), " "))
+	if *out == "" {
+		_, err = w.Write(src)
+		return err
+	}
+	return os.WriteFile(*out, src, 0666)
+}
+
+// cGen generates the C declarations of struct types.
+type cGen struct {
+	af alignbinary.AlignFactor
+	// done records the types generated or being generated.
+	done map[*types.TypeName]bool
+	// decls are the typedefs of the struct types in dependency order.
+	decls bytes.Buffer
+	// asserts are the static assertions of the layouts.
+	asserts bytes.Buffer
+	// pads counts the padding members of the struct being generated.
+	pads int
+}
+
+// header returns the whole header source with the include guard.
+func (g *cGen) header(guard, cmdline string) []byte {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by \"%v\"; DO NOT EDIT.\n\n", cmdline)
+	fmt.Fprintf(buf, "#ifndef %v\n#define %v\n\n", guard, guard)
+	fmt.Fprintf(buf, "#include <stdbool.h>\n#include <stddef.h>\n#include <stdint.h>\n\n")
+	if g.af != alignbinary.AlignDefault {
+		fmt.Fprintf(buf, "#pragma pack(push, %v)\n\n", g.af)
+	}
+	buf.Write(g.decls.Bytes())
+	if g.af != alignbinary.AlignDefault {
+		fmt.Fprintf(buf, "#pragma pack(pop)\n\n")
+	}
+	buf.Write(g.asserts.Bytes())
+	fmt.Fprintf(buf, "\n#endif // %v\n", guard)
+	return buf.Bytes()
+}
+
+// genType generates the typedef of the named struct type obj,
+// after the ones of the named struct types it depends on.
+func (g *cGen) genType(obj *types.TypeName) error {
+	if g.done[obj] {
+		return nil
+	}
+	g.done[obj] = true
+	st := obj.Type().Underlying().(*types.Struct)
+	for i := 0; i < st.NumFields(); i++ {
+		if dep := namedStruct(st.Field(i).Type()); dep != nil {
+			if err := g.genType(dep); err != nil {
+				return err
+			}
+		}
+	}
+	sl, err := typeLayout(obj, g.af)
+	if err != nil {
+		return err
+	}
+	g.pads = 0
+	fmt.Fprintf(&g.decls, "typedef struct {\n")
+	g.genFields(sl.Fields, st, sl.TrailingPad, 1)
+	fmt.Fprintf(&g.decls, "} %v;\n\n", obj.Name())
+
+	g.genAsserts(obj.Name(), "", sl.Fields, st)
+	fmt.Fprintf(&g.asserts, "_Static_assert(sizeof(%v) == %v, \"size of %v\");\n", obj.Name(), sl.Size, obj.Name())
+	return nil
+}
+
+// genFields generates the members of the struct type st described by the fields,
+// with explicit padding members, indented by the depth.
+func (g *cGen) genFields(fields []alignbinary.FieldLayout, st *types.Struct, trailingPad uintptr, depth int) {
+	indent := strings.Repeat("\t", depth)
+	for i, f := range fields {
+		if f.PadBefore > 0 {
+			g.genPad(indent, f.PadBefore)
+		}
+		v := st.Field(i)
+		name := cMemberName(v, i)
+		base, dims := arrayDims(v.Type())
+		if s, ok := base.(*types.Struct); ok {
+			// An anonymous struct is declared inline.
+			fmt.Fprintf(&g.decls, "%vstruct {\n", indent)
+			var pad uintptr
+			if n := len(f.Fields); n > 0 {
+				pad = f.Fields[n-1].PadAfter
+			}
+			g.genFields(f.Fields, s, pad, depth+1)
+			fmt.Fprintf(&g.decls, "%v} %v%v;\n", indent, name, dims)
+			continue
+		}
+		typeName, suffix := cTypeName(base)
+		fmt.Fprintf(&g.decls, "%v%v %v%v%v;\n", indent, typeName, name, dims, suffix)
+	}
+	if trailingPad > 0 {
+		g.genPad(indent, trailingPad)
+	}
+}
+
+// genAsserts generates the static assertions of the offsets of the fields of the
+// struct type st in the typedef typeName, and of the fields of its anonymous structs,
+// whose member designators are prefixed by the prefix, e.g. "tags[0].".
+func (g *cGen) genAsserts(typeName, prefix string, fields []alignbinary.FieldLayout, st *types.Struct) {
+	for i, f := range fields {
+		v := st.Field(i)
+		if v.Name() == "_" {
+			continue
+		}
+		member := prefix + cMemberName(v, i)
+		fmt.Fprintf(&g.asserts, "_Static_assert(offsetof(%v, %v) == %v, \"offset of %v.%v\");\n",
+			typeName, member, f.Offset, typeName, member)
+		// The fields of an array of structs describe the first element.
+		base, dims := arrayDims(v.Type())
+		if s, ok := base.(*types.Struct); ok {
+			g.genAsserts(typeName, member+strings.Repeat("[0]", strings.Count(dims, "["))+".", f.Fields, s)
+		}
+	}
+}
+
+func (g *cGen) genPad(indent string, n uintptr) {
+	fmt.Fprintf(&g.decls, "%vuint8_t _pad%v[%v];\n", indent, g.pads, n)
+	g.pads++
+}
+
+// arrayDims returns the element type of t and the C array declarators, e.g. "[2][3]",
+// or t and an empty string if t is not an array.
+func arrayDims(t types.Type) (types.Type, string) {
+	var dims string
+	for {
+		a, ok := t.Underlying().(*types.Array)
+		if !ok {
+			break
+		}
+		dims += fmt.Sprintf("[%v]", a.Len())
+		t = a.Elem()
+	}
+	if _, ok := t.(*types.Named); !ok {
+		// Use the anonymous struct directly.
+		t = t.Underlying()
+	}
+	return t, dims
+}
+
+// namedStruct returns the named struct type of t, or of the elements if t is an array,
+// or nil if there is none.
+func namedStruct(t types.Type) *types.TypeName {
+	t, _ = arrayDims(t)
+	if n, ok := t.(*types.Named); ok {
+		if _, ok = n.Underlying().(*types.Struct); ok {
+			return n.Obj()
+		}
+	}
+	return nil
+}
+
+// cKeywords are the C11 keywords, and the macros of the included headers,
+// which cannot be member names.
+var cKeywords = map[string]bool{
+	"auto": true, "break": true, "case": true, "char": true, "const": true, "continue": true,
+	"default": true, "do": true, "double": true, "else": true, "enum": true, "extern": true,
+	"float": true, "for": true, "goto": true, "if": true, "inline": true, "int": true,
+	"long": true, "register": true, "restrict": true, "return": true, "short": true, "signed": true,
+	"sizeof": true, "static": true, "struct": true, "switch": true, "typedef": true, "union": true,
+	"unsigned": true, "void": true, "volatile": true, "while": true,
+	"_Alignas": true, "_Alignof": true, "_Atomic": true, "_Bool": true, "_Complex": true, "_Generic": true,
+	"_Imaginary": true, "_Noreturn": true, "_Static_assert": true, "_Thread_local": true,
+	"bool": true, "true": true, "false": true, "NULL": true,
+}
+
+// cMemberName returns the C member name of the i-th field v of a struct:
+// the Go name, suffixed by an underscore if it is a C keyword.
+func cMemberName(v *types.Var, i int) string {
+	switch name := v.Name(); {
+	case name == "_":
+		return fmt.Sprintf("_blank%v", i)
+	case cKeywords[name]:
+		return name + "_"
+	default:
+		return name
+	}
+}
+
+// cBasicTypes maps the kinds of fixed-size basic types to the C types.
+// The complex types are declared as arrays of two floating-point values.
+var cBasicTypes = map[types.BasicKind]string{
+	types.Bool:       "bool",
+	types.Int8:       "int8_t",
+	types.Uint8:      "uint8_t",
+	types.Int16:      "int16_t",
+	types.Uint16:     "uint16_t",
+	types.Int32:      "int32_t",
+	types.Uint32:     "uint32_t",
+	types.Int64:      "int64_t",
+	types.Uint64:     "uint64_t",
+	types.Float32:    "float",
+	types.Float64:    "double",
+	types.Complex64:  "float",
+	types.Complex128: "double",
+}
+
+// cTypeName returns the C type name of the non-array type t which has been checked
+// by reflectType, and the array declarator of a complex type, e.g. "[2]".
+func cTypeName(t types.Type) (string, string) {
+	if n := namedStruct(t); n != nil {
+		return n.Name(), ""
+	}
+	switch kind := t.Underlying().(*types.Basic).Kind(); kind {
+	case types.Complex64, types.Complex128:
+		return cBasicTypes[kind], "[2]"
+	default:
+		return cBasicTypes[kind], ""
+	}
+}
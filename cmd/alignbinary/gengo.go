@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const genGoUsage = "gen-go [-package name] [-o file] [-test file] header.h"
+
+// runGenGo generates the Go struct types with the same layouts as the structs
+// declared in a C header.
+func runGenGo(w io.Writer, args []string) error {
+	fs := newFlagSet("gen-go", genGoUsage)
+	pkgName := fs.String("package", "", "package name, derived from the header name by default")
+	out := fs.String("o", "", "output file, the standard output by default")
+	testOut := fs.String("test", "", "output file of a test asserting the sizes, none by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	src, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	h, err := parseC(string(src))
+	if err != nil {
+		return fmt.Errorf("%v: %v", fs.Arg(0), err)
+	}
+	if *pkgName == "" {
+		*pkgName = packageName(fs.Arg(0))
+	}
+	g := &goGen{pkg: *pkgName, cmdline: strings.Join(append([]string{"alignbinary", "gen-go"}, args...), " ")}
+	code, err := g.source(h)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		if _, err = w.Write(code); err != nil {
+			return err
+		}
+	} else if err = os.WriteFile(*out, code, 0666); err != nil {
+		return err
+	}
+	if *testOut == "" {
+		return nil
+	}
+	if code, err = g.testSource(); err != nil {
+		return err
+	}
+	return os.WriteFile(*testOut, code, 0666)
+}
+
+// packageName derives a package name from the file name.
+func packageName(file string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	name = strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "c" + name
+	}
+	return name
+}
+
+// goGen generates the Go source of the types in a C header.
+type goGen struct {
+	pkg     string
+	cmdline string
+	buf     bytes.Buffer
+	// structs are the Go names and the C sizes of the generated structs.
+	structs []goStruct
+}
+
+type goStruct struct {
+	name string
+	size int
+}
+
+// source returns the formatted Go source of the constants and types in h.
+func (g *goGen) source(h *cHeader) ([]byte, error) {
+	g.header()
+	fmt.Fprintf(&g.buf, "// The struct types include explicit padding fields to reproduce the C layouts,\n")
+	fmt.Fprintf(&g.buf, "// so they must be encoded with the alignbinary.Align1Byte.\n\n")
+	if len(h.consts) > 0 {
+		fmt.Fprintf(&g.buf, "const (\n")
+		for _, c := range h.consts {
+			fmt.Fprintf(&g.buf, "%v = %v\n", exportedName(c.name), c.value)
+		}
+		fmt.Fprintf(&g.buf, ")\n\n")
+	}
+	for _, t := range h.types {
+		g.genType(exportedName(t.name), t)
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+func (g *goGen) header() {
+	fmt.Fprintf(&g.buf, "// Code generated by \"%v\"; DO NOT EDIT.\n\n", g.cmdline)
+	fmt.Fprintf(&g.buf, "package %v\n\n", g.pkg)
+}
+
+// genType generates the Go type declaration of the C struct or union t.
+func (g *goGen) genType(name string, t *cType) {
+	switch {
+	case t.elem != nil:
+		fmt.Fprintf(&g.buf, "type %v = %v\n\n", name, exportedName(t.elem.name))
+	case t.kind == cUnion:
+		members := make([]string, len(t.fields))
+		for i, f := range t.fields {
+			members[i] = f.name
+		}
+		fmt.Fprintf(&g.buf, "// %v is the C union of %v, size %v, alignment %v.\n", name, strings.Join(members, ", "), t.size, t.align)
+		fmt.Fprintf(&g.buf, "type %v [%v]byte\n\n", name, t.size)
+	default:
+		// Generate the anonymous structs of the fields after t.
+		var nested []func()
+		fmt.Fprintf(&g.buf, "// %v is the C struct of size %v, alignment %v.\n", name, t.size, t.align)
+		fmt.Fprintf(&g.buf, "type %v struct {\n", name)
+		var end int
+		for _, f := range t.fields {
+			if f.offset > end {
+				fmt.Fprintf(&g.buf, "_ [%v]byte\n", f.offset-end)
+			}
+			end = f.offset + f.typ.size
+			fieldName := exportedName(f.name)
+			if r := recordElem(f.typ); r != nil && r.kind == cStruct && r.name == "" {
+				r.name = name + fieldName
+				nested = append(nested, func() { g.genType(r.name, r) })
+			}
+			fmt.Fprintf(&g.buf, "%v %v // offset %v\n", fieldName, goTypeName(f.typ), f.offset)
+		}
+		if t.size > end {
+			fmt.Fprintf(&g.buf, "_ [%v]byte\n", t.size-end)
+		}
+		fmt.Fprintf(&g.buf, "}\n\n")
+		g.structs = append(g.structs, goStruct{name, t.size})
+		for _, fn := range nested {
+			fn()
+		}
+	}
+}
+
+// recordElem returns the struct or union of t, or of the elements if t is an array,
+// or nil if there is none.
+func recordElem(t *cType) *cType {
+	for t.kind == cArray {
+		t = t.elem
+	}
+	if t.kind == cStruct || t.kind == cUnion {
+		return t
+	}
+	return nil
+}
+
+// goTypeName returns the Go type expression of t.
+func goTypeName(t *cType) string {
+	switch t.kind {
+	case cArray:
+		return fmt.Sprintf("[%v]%v", t.len, goTypeName(t.elem))
+	case cUnion:
+		if t.name == "" {
+			return fmt.Sprintf("[%v]byte", t.size)
+		}
+		return exportedName(t.name)
+	case cStruct:
+		return exportedName(t.name)
+	}
+	return t.name
+}
+
+// exportedName returns the exported Go name of a C name.
+func exportedName(name string) string {
+	if name == "" || name[0] == '_' {
+		return "X" + name
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// testSource returns the formatted Go source of a test asserting the sizes
+// of the generated structs.
+func (g *goGen) testSource() ([]byte, error) {
+	g.buf.Reset()
+	g.header()
+	fmt.Fprintf(&g.buf, "import (\n\"reflect\"\n\"testing\"\n\n\"github.com/happyxcj/alignbinary\"\n)\n\n")
+	fmt.Fprintf(&g.buf, "func TestCLayouts(t *testing.T) {\n")
+	fmt.Fprintf(&g.buf, "for _, c := range []struct {\ntyp reflect.Type\nsize uintptr\n}{\n")
+	for _, s := range g.structs {
+		fmt.Fprintf(&g.buf, "{reflect.TypeOf(%v{}), %v},\n", s.name, s.size)
+	}
+	fmt.Fprintf(&g.buf, "} {\n")
+	fmt.Fprintf(&g.buf, "l, err := alignbinary.Layout(c.typ, alignbinary.Align1Byte)\n")
+	fmt.Fprintf(&g.buf, "if err != nil {\nt.Errorf(\"%%v: %%v\", c.typ, err)\ncontinue\n}\n")
+	fmt.Fprintf(&g.buf, "if l.Size != c.size {\nt.Errorf(\"%%v: have size %%v, want %%v\", c.typ, l.Size, c.size)\n}\n")
+	fmt.Fprintf(&g.buf, "}\n}\n")
+	return format.Source(g.buf.Bytes())
+}
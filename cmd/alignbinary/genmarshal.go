@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+const genMarshalUsage = "gen-marshal -align n -type T[,T...] [-o file] [dir]"
+
+// runGenMarshal generates the reflection-free methods implementing the
+// alignbinary.AlignedMarshaler and alignbinary.AlignedUnmarshaler for struct types.
+// It's designed to be run by go:generate:
+//
+//	//go:generate alignbinary gen-marshal -align 4 -type Msg
+//
+// The methods are straight-line code at constant offsets, e.g. order.PutUint32(b[12:], ...),
+// the arrays of up to maxUnrolled elements are unrolled, and the longer ones are
+// encoded by loops to bound the size of the code.
+//
+// The AlignDefault is rejected, because its layouts depend on the platform
+// running the generator, e.g. int64 is 4-byte aligned on 386, so the methods
+// generated for it wouldn't match the EncoderGroup on the other platforms.
+func runGenMarshal(w io.Writer, args []string) error {
+	fs := newFlagSet("gen-marshal", genMarshalUsage)
+	af := fs.Uint("align", alignbinary.AlignDefault, "alignment factor: 1, 2, 4 or 8; must be set")
+	typeNames := fs.String("type", "", "comma-separated list of struct type names; must be set")
+	out := fs.String("o", "", "output file, <type>_aligned.go in the package directory by default; - for the standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *typeNames == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := checkAlign(*af); err != nil {
+		return err
+	}
+	if *af == alignbinary.AlignDefault {
+		return fmt.Errorf("alignment factor must be 1, 2, 4 or 8, the layouts of the system default depend on the platform")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(*typeNames, ",")
+	objs, err := lookupStructs(pkg, names)
+	if err != nil {
+		return err
+	}
+	g := &marshalGen{af: alignbinary.AlignFactor(*af), qf: types.RelativeTo(pkg)}
+	for _, obj := range objs {
+		if err = g.genType(obj); err != nil {
+			return err
+		}
+	}
+	src, err := g.source(pkg.Name(), strings.Join(append([]string{"alignbinary", "gen-marshal"}, args...), " "))
+	if err != nil {
+		return err
+	}
+	switch *out {
+	case "-":
+		_, err = w.Write(src)
+		return err
+	case "":
+		*out = filepath.Join(dir, strings.ToLower(names[0])+"_aligned.go")
+	}
+	return os.WriteFile(*out, src, 0666)
+}
+
+// marshalGen generates the methods of struct types.
+type marshalGen struct {
+	af alignbinary.AlignFactor
+	qf types.Qualifier
+	// buf is the generated methods of all types.
+	buf bytes.Buffer
+	// body is the statements of the method being generated.
+	body bytes.Buffer
+	// usesMath reports whether the package math is used.
+	usesMath bool
+	// loops is the depth of the loops of the statement being generated.
+	loops int
+}
+
+// source returns the formatted source of the generated methods.
+func (g *marshalGen) source(pkgName, cmdline string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by \"%v\"; DO NOT EDIT.\n\n", cmdline)
+	fmt.Fprintf(buf, "package %v\n\n", pkgName)
+	fmt.Fprintf(buf, "import (\n\"encoding/binary\"\n\"io\"\n")
+	if g.usesMath {
+		fmt.Fprintf(buf, "\"math\"\n")
+	}
+	fmt.Fprintf(buf, "\n\"github.com/happyxcj/alignbinary\"\n)\n\n")
+	buf.Write(g.buf.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// genType generates the methods of the named struct type obj.
+func (g *marshalGen) genType(obj *types.TypeName) error {
+	sl, err := typeLayout(obj, g.af)
+	if err != nil {
+		return err
+	}
+	name := obj.Name()
+	st := obj.Type().Underlying().(*types.Struct)
+	afName := alignNames[g.af]
+
+	fmt.Fprintf(&g.buf, "// AlignedLayout implements the alignbinary.AlignedMarshaler and the alignbinary.AlignedUnmarshaler.\n")
+	fmt.Fprintf(&g.buf, "func (%v) AlignedLayout() (alignbinary.AlignFactor, int) {\nreturn alignbinary.%v, %v\n}\n\n", name, afName, sl.Size)
+
+	g.body.Reset()
+	g.genStruct("m", offset{}, st, true)
+	fmt.Fprintf(&g.buf, "// AppendAligned implements the alignbinary.AlignedMarshaler.\n")
+	fmt.Fprintf(&g.buf, "func (m %v) AppendAligned(buf []byte, order binary.ByteOrder) []byte {\n", name)
+	fmt.Fprintf(&g.buf, "n := len(buf)\nbuf = append(buf, make([]byte, %v)...)\nb := buf[n:]\n", sl.Size)
+	g.buf.Write(g.body.Bytes())
+	fmt.Fprintf(&g.buf, "return buf\n}\n\n")
+
+	fmt.Fprintf(&g.buf, "// MarshalAligned returns the binary representation of m.\n")
+	fmt.Fprintf(&g.buf, "func (m %v) MarshalAligned(order binary.ByteOrder) []byte {\n", name)
+	fmt.Fprintf(&g.buf, "return m.AppendAligned(make([]byte, 0, %v), order)\n}\n\n", sl.Size)
+
+	g.body.Reset()
+	g.genStruct("m", offset{}, st, false)
+	fmt.Fprintf(&g.buf, "// UnmarshalAligned implements the alignbinary.AlignedUnmarshaler.\n")
+	fmt.Fprintf(&g.buf, "func (m *%v) UnmarshalAligned(b []byte, order binary.ByteOrder) error {\n", name)
+	fmt.Fprintf(&g.buf, "if len(b) < %v {\nreturn io.ErrUnexpectedEOF\n}\n", sl.Size)
+	g.buf.Write(g.body.Bytes())
+	fmt.Fprintf(&g.buf, "return nil\n}\n\n")
+	return nil
+}
+
+// maxUnrolled is the maximum length of the arrays unrolled by gen-marshal.
+const maxUnrolled = 16
+
+// alignNames are the names of the alignment factors in the package alignbinary.
+var alignNames = map[alignbinary.AlignFactor]string{
+	alignbinary.Align1Byte: "Align1Byte",
+	alignbinary.Align2Byte: "Align2Byte",
+	alignbinary.Align4Byte: "Align4Byte",
+	alignbinary.Align8Byte: "Align8Byte",
+}
+
+// offset is the offset expression of a value, the sum of the variable terms and n.
+type offset struct {
+	terms string
+	n     uintptr
+}
+
+// add returns the sum of o and the constant n.
+func (o offset) add(n uintptr) offset {
+	return offset{o.terms, o.n + n}
+}
+
+// addTerm returns the sum of o and the variable term.
+func (o offset) addTerm(term string) offset {
+	if o.terms != "" {
+		term = o.terms + "+" + term
+	}
+	return offset{term, o.n}
+}
+
+func (o offset) String() string {
+	switch {
+	case o.terms == "":
+		return fmt.Sprint(o.n)
+	case o.n == 0:
+		return o.terms
+	}
+	return fmt.Sprintf("%v+%v", o.terms, o.n)
+}
+
+// slice returns the slice expression of the named slice starting at o.
+func (o offset) slice(name string) string {
+	if o.terms == "" && o.n == 0 {
+		return name
+	}
+	return fmt.Sprintf("%v[%v:]", name, o)
+}
+
+// genStruct generates the statements to encode or decode the struct st
+// referred by the expr at the offset off.
+// The blank and unexported fields are skipped like the package alignbinary does.
+func (g *marshalGen) genStruct(expr string, off offset, st *types.Struct, encode bool) {
+	t, _ := reflectType(st, expr)
+	sl, _ := alignbinary.Layout(t, g.af)
+	for i, f := range sl.Fields {
+		v := st.Field(i)
+		if v.Name() == "_" || !v.Exported() {
+			continue
+		}
+		g.genValue(expr+"."+v.Name(), off.add(f.Offset), v.Type(), encode)
+	}
+}
+
+// genValue generates the statements to encode or decode the value of type t
+// referred by the expr at the offset off.
+func (g *marshalGen) genValue(expr string, off offset, t types.Type, encode bool) {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		g.genStruct(expr, off, u, encode)
+	case *types.Array:
+		size := typeSize(u.Elem(), g.af)
+		if u.Len() <= maxUnrolled {
+			for i := int64(0); i < u.Len(); i++ {
+				g.genValue(fmt.Sprintf("%v[%v]", expr, i), off.add(uintptr(i)*size), u.Elem(), encode)
+			}
+			return
+		}
+		i := fmt.Sprintf("i%v", g.loops)
+		fmt.Fprintf(&g.body, "for %v := 0; %v < %v; %v++ {\n", i, i, u.Len(), i)
+		term := i
+		if size != 1 {
+			term = fmt.Sprintf("%v*%v", i, size)
+		}
+		g.loops++
+		g.genValue(expr+"["+i+"]", off.addTerm(term), u.Elem(), encode)
+		g.loops--
+		fmt.Fprintf(&g.body, "}\n")
+	case *types.Basic:
+		if encode {
+			g.genEncodeBasic(expr, off, u.Kind())
+		} else {
+			g.genDecodeBasic(expr, off, types.TypeString(t, g.qf), u.Kind())
+		}
+	}
+}
+
+// typeSize returns the size of t checked by reflectType based on the af.
+func typeSize(t types.Type, af alignbinary.AlignFactor) uintptr {
+	switch u := t.Underlying().(type) {
+	case *types.Array:
+		return uintptr(u.Len()) * typeSize(u.Elem(), af)
+	case *types.Struct:
+		rt, _ := reflectType(u, "")
+		sl, _ := alignbinary.Layout(rt, af)
+		return sl.Size
+	default:
+		rt, _ := reflectType(u, "")
+		return rt.Size()
+	}
+}
+
+func (g *marshalGen) genEncodeBasic(expr string, off offset, kind types.BasicKind) {
+	b := off.slice("b")
+	switch kind {
+	case types.Bool:
+		fmt.Fprintf(&g.body, "if %v {\nb[%v] = 1\n}\n", expr, off)
+	case types.Int8, types.Uint8:
+		fmt.Fprintf(&g.body, "b[%v] = byte(%v)\n", off, expr)
+	case types.Int16, types.Uint16:
+		fmt.Fprintf(&g.body, "order.PutUint16(%v, uint16(%v))\n", b, expr)
+	case types.Int32, types.Uint32:
+		fmt.Fprintf(&g.body, "order.PutUint32(%v, uint32(%v))\n", b, expr)
+	case types.Int64, types.Uint64:
+		fmt.Fprintf(&g.body, "order.PutUint64(%v, uint64(%v))\n", b, expr)
+	case types.Float32:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "order.PutUint32(%v, math.Float32bits(float32(%v)))\n", b, expr)
+	case types.Float64:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "order.PutUint64(%v, math.Float64bits(float64(%v)))\n", b, expr)
+	case types.Complex64:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "order.PutUint32(%v, math.Float32bits(real(%v)))\n", b, expr)
+		fmt.Fprintf(&g.body, "order.PutUint32(%v, math.Float32bits(imag(%v)))\n", off.add(4).slice("b"), expr)
+	case types.Complex128:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "order.PutUint64(%v, math.Float64bits(real(%v)))\n", b, expr)
+		fmt.Fprintf(&g.body, "order.PutUint64(%v, math.Float64bits(imag(%v)))\n", off.add(8).slice("b"), expr)
+	}
+}
+
+func (g *marshalGen) genDecodeBasic(expr string, off offset, typ string, kind types.BasicKind) {
+	b := off.slice("b")
+	switch kind {
+	case types.Bool:
+		fmt.Fprintf(&g.body, "%v = b[%v] != 0\n", expr, off)
+	case types.Int8, types.Uint8:
+		fmt.Fprintf(&g.body, "%v = %v(b[%v])\n", expr, typ, off)
+	case types.Int16, types.Uint16:
+		fmt.Fprintf(&g.body, "%v = %v(order.Uint16(%v))\n", expr, typ, b)
+	case types.Int32, types.Uint32:
+		fmt.Fprintf(&g.body, "%v = %v(order.Uint32(%v))\n", expr, typ, b)
+	case types.Int64, types.Uint64:
+		fmt.Fprintf(&g.body, "%v = %v(order.Uint64(%v))\n", expr, typ, b)
+	case types.Float32:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "%v = %v(math.Float32frombits(order.Uint32(%v)))\n", expr, typ, b)
+	case types.Float64:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "%v = %v(math.Float64frombits(order.Uint64(%v)))\n", expr, typ, b)
+	case types.Complex64:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "%v = %v(complex(math.Float32frombits(order.Uint32(%v)), math.Float32frombits(order.Uint32(%v))))\n",
+			expr, typ, b, off.add(4).slice("b"))
+	case types.Complex128:
+		g.usesMath = true
+		fmt.Fprintf(&g.body, "%v = %v(complex(math.Float64frombits(order.Uint64(%v)), math.Float64frombits(order.Uint64(%v))))\n",
+			expr, typ, b, off.add(8).slice("b"))
+	}
+}
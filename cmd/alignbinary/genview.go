@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+const genViewUsage = "gen-view -align n [-order little|big|native] -type T[,T...] [-o file] [dir]"
+
+// runGenView generates the view types accessing the fields of the binary
+// representations of struct types in place, e.g. the MsgView of the type Msg:
+//
+//	type MsgView []byte
+//
+//	func (v MsgView) Seq() uint32
+//	func (v MsgView) SetSeq(x uint32)
+//
+// The views of the nested structs and the arrays of structs are generated as well.
+// The AlignDefault is rejected as by runGenMarshal, since the offsets would
+// depend on the platform running the generator.
+func runGenView(w io.Writer, args []string) error {
+	fs := newFlagSet("gen-view", genViewUsage)
+	af := fs.Uint("align", alignbinary.AlignDefault, "alignment factor: 1, 2, 4 or 8; must be set")
+	order := fs.String("order", "little", "byte order: little, big or native")
+	typeNames := fs.String("type", "", "comma-separated list of struct type names; must be set")
+	out := fs.String("o", "", "output file, <type>_view.go in the package directory by default; - for the standard output")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *typeNames == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := checkAlign(*af); err != nil {
+		return err
+	}
+	if *af == alignbinary.AlignDefault {
+		return fmt.Errorf("alignment factor must be 1, 2, 4 or 8, the layouts of the system default depend on the platform")
+	}
+	orderName, ok := orderNames[*order]
+	if !ok {
+		return fmt.Errorf("invalid byte order %v, must be little, big or native", *order)
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		return err
+	}
+	names := strings.Split(*typeNames, ",")
+	objs, err := lookupStructs(pkg, names)
+	if err != nil {
+		return err
+	}
+	g := &viewGen{
+		af:    alignbinary.AlignFactor(*af),
+		order: "binary." + orderName,
+		qf:    types.RelativeTo(pkg),
+		done:  make(map[string]bool),
+	}
+	for _, obj := range objs {
+		if _, err = typeLayout(obj, g.af); err != nil {
+			return err
+		}
+		g.genView(obj.Name(), obj.Type().Underlying().(*types.Struct))
+	}
+	src, err := g.source(pkg.Name(), strings.Join(append([]string{"alignbinary", "gen-view"}, args...), " "))
+	if err != nil {
+		return err
+	}
+	switch *out {
+	case "-":
+		_, err = w.Write(src)
+		return err
+	case "":
+		*out = filepath.Join(dir, strings.ToLower(names[0])+"_view.go")
+	}
+	return os.WriteFile(*out, src, 0666)
+}
+
+// orderNames maps the byte order flags to the byte orders in the package encoding/binary.
+var orderNames = map[string]string{
+	"little": "LittleEndian",
+	"big":    "BigEndian",
+	"native": "NativeEndian",
+}
+
+// viewGen generates the view types of struct types.
+type viewGen struct {
+	af alignbinary.AlignFactor
+	// order is the expression of the byte order.
+	order string
+	qf    types.Qualifier
+	// done records the views generated or being generated by the names of structs.
+	done map[string]bool
+	buf  bytes.Buffer
+	// usesMath reports whether the package math is used.
+	usesMath bool
+}
+
+// source returns the formatted source of the generated views.
+func (g *viewGen) source(pkgName, cmdline string) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by \"%v\"; DO NOT EDIT.\n\n", cmdline)
+	fmt.Fprintf(buf, "package %v\n\n", pkgName)
+	fmt.Fprintf(buf, "import (\n\"encoding/binary\"\n")
+	if g.usesMath {
+		fmt.Fprintf(buf, "\"math\"\n")
+	}
+	fmt.Fprintf(buf, ")\n\n")
+	buf.Write(g.buf.Bytes())
+	return format.Source(buf.Bytes())
+}
+
+// genView generates the view of the struct st named by the name,
+// then the views of the structs in the fields.
+func (g *viewGen) genView(name string, st *types.Struct) {
+	if g.done[name] {
+		return
+	}
+	g.done[name] = true
+	view := name + "View"
+	size := typeSize(st, g.af)
+	fmt.Fprintf(&g.buf, "// %vSize is the size of the binary representation of %v.\n", view, name)
+	fmt.Fprintf(&g.buf, "const %vSize = %v\n\n", view, size)
+	fmt.Fprintf(&g.buf, "// %v accesses the fields of the binary representation of %v in place.\n", view, name)
+	fmt.Fprintf(&g.buf, "type %v []byte\n\n", view)
+
+	var nested []func()
+	t, _ := reflectType(st, name)
+	sl, _ := alignbinary.Layout(t, g.af)
+	for i, f := range sl.Fields {
+		v := st.Field(i)
+		if v.Name() == "_" || !v.Exported() {
+			continue
+		}
+		// Collect the dimensions of the arrays as the index parameters.
+		ft := v.Type()
+		off := offset{n: f.Offset}
+		var params, checks []string
+		for {
+			a, ok := ft.Underlying().(*types.Array)
+			if !ok {
+				break
+			}
+			p := string(rune('i' + len(params)))
+			params = append(params, p)
+			checks = append(checks, fmt.Sprintf("if uint(%v) >= %v {\npanic(\"%v.%v: index out of range\")\n}\n", p, a.Len(), view, v.Name()))
+			ft = a.Elem()
+			term := p
+			if elemSize := typeSize(ft, g.af); elemSize != 1 {
+				term = fmt.Sprintf("%v*%v", p, elemSize)
+			}
+			off = off.addTerm(term)
+		}
+		m := viewMethod{view: view, field: v.Name(), off: off, checks: strings.Join(checks, "")}
+		if len(params) > 0 {
+			m.params = strings.Join(params, ", ") + " int"
+		}
+		switch u := ft.Underlying().(type) {
+		case *types.Struct:
+			elemName := name + v.Name()
+			if n, ok := ft.(*types.Named); ok {
+				elemName = n.Obj().Name()
+			}
+			nested = append(nested, func() { g.genView(elemName, u) })
+			g.genSubView(m, elemName+"View", typeSize(u, g.af))
+		case *types.Basic:
+			g.genAccessors(m, types.TypeString(ft, g.qf), u.Kind())
+		}
+	}
+	for _, fn := range nested {
+		fn()
+	}
+}
+
+// viewMethod describes the accessors of a field.
+type viewMethod struct {
+	view  string
+	field string
+	// off is the offset of the field, or of the element if it's an array.
+	off offset
+	// params are the index parameters of the arrays, e.g. "i, j int".
+	params string
+	// checks are the statements checking the indexes.
+	checks string
+}
+
+// genSubView generates the accessor of the struct field described by m.
+func (g *viewGen) genSubView(m viewMethod, view string, size uintptr) {
+	fmt.Fprintf(&g.buf, "// %v returns the view of the %v.\n", m.field, m.field)
+	fmt.Fprintf(&g.buf, "func (v %v) %v(%v) %v {\n%v", m.view, m.field, m.params, view, m.checks)
+	fmt.Fprintf(&g.buf, "return %v(v[%v : %v])\n}\n\n", view, m.off, m.off.add(size))
+}
+
+// genAccessors generates the getter and setter of the basic type field described by m.
+func (g *viewGen) genAccessors(m viewMethod, typ string, kind types.BasicKind) {
+	b := m.off.slice("v")
+	at := fmt.Sprintf("v[%v]", m.off)
+	var get string
+	switch kind {
+	case types.Bool:
+		get = at + " != 0"
+	case types.Int8, types.Uint8:
+		get = fmt.Sprintf("%v(%v)", typ, at)
+	case types.Int16, types.Uint16:
+		get = fmt.Sprintf("%v(%v.Uint16(%v))", typ, g.order, b)
+	case types.Int32, types.Uint32:
+		get = fmt.Sprintf("%v(%v.Uint32(%v))", typ, g.order, b)
+	case types.Int64, types.Uint64:
+		get = fmt.Sprintf("%v(%v.Uint64(%v))", typ, g.order, b)
+	case types.Float32:
+		get = fmt.Sprintf("%v(math.Float32frombits(%v.Uint32(%v)))", typ, g.order, b)
+	case types.Float64:
+		get = fmt.Sprintf("%v(math.Float64frombits(%v.Uint64(%v)))", typ, g.order, b)
+	case types.Complex64:
+		get = fmt.Sprintf("%v(complex(math.Float32frombits(%v.Uint32(%v)), math.Float32frombits(%v.Uint32(v[%v:]))))",
+			typ, g.order, b, g.order, m.off.add(4))
+	case types.Complex128:
+		get = fmt.Sprintf("%v(complex(math.Float64frombits(%v.Uint64(%v)), math.Float64frombits(%v.Uint64(v[%v:]))))",
+			typ, g.order, b, g.order, m.off.add(8))
+	}
+	fmt.Fprintf(&g.buf, "// %v returns the %v.\n", m.field, m.field)
+	fmt.Fprintf(&g.buf, "func (v %v) %v(%v) %v {\n%v", m.view, m.field, m.params, typ, m.checks)
+	fmt.Fprintf(&g.buf, "return %v\n}\n\n", get)
+
+	params := "x " + typ
+	if m.params != "" {
+		params = m.params + ", " + params
+	}
+	fmt.Fprintf(&g.buf, "// Set%v sets the %v.\n", m.field, m.field)
+	fmt.Fprintf(&g.buf, "func (v %v) Set%v(%v) {\n%v", m.view, m.field, params, m.checks)
+	switch kind {
+	case types.Bool:
+		fmt.Fprintf(&g.buf, "if x {\n%v = 1\n} else {\n%v = 0\n}\n", at, at)
+	case types.Int8, types.Uint8:
+		fmt.Fprintf(&g.buf, "%v = byte(x)\n", at)
+	case types.Int16, types.Uint16:
+		fmt.Fprintf(&g.buf, "%v.PutUint16(%v, uint16(x))\n", g.order, b)
+	case types.Int32, types.Uint32:
+		fmt.Fprintf(&g.buf, "%v.PutUint32(%v, uint32(x))\n", g.order, b)
+	case types.Int64, types.Uint64:
+		fmt.Fprintf(&g.buf, "%v.PutUint64(%v, uint64(x))\n", g.order, b)
+	case types.Float32:
+		fmt.Fprintf(&g.buf, "%v.PutUint32(%v, math.Float32bits(float32(x)))\n", g.order, b)
+	case types.Float64:
+		fmt.Fprintf(&g.buf, "%v.PutUint64(%v, math.Float64bits(float64(x)))\n", g.order, b)
+	case types.Complex64:
+		fmt.Fprintf(&g.buf, "%v.PutUint32(%v, math.Float32bits(real(x)))\n", g.order, b)
+		fmt.Fprintf(&g.buf, "%v.PutUint32(v[%v:], math.Float32bits(imag(x)))\n", g.order, m.off.add(4))
+	case types.Complex128:
+		fmt.Fprintf(&g.buf, "%v.PutUint64(%v, math.Float64bits(real(x)))\n", g.order, b)
+		fmt.Fprintf(&g.buf, "%v.PutUint64(v[%v:], math.Float64bits(imag(x)))\n", g.order, m.off.add(8))
+	}
+	fmt.Fprintf(&g.buf, "}\n\n")
+	switch kind {
+	case types.Float32, types.Float64, types.Complex64, types.Complex128:
+		g.usesMath = true
+	}
+}
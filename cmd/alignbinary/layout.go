@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+const layoutUsage = "layout [-align n] [-format text|json] dir [TypeName...]"
+
+// runLayout prints the layouts of the struct types in a package.
+func runLayout(w io.Writer, args []string) error {
+	fs := newFlagSet("layout", layoutUsage)
+	af := fs.Uint("align", alignbinary.AlignDefault, "alignment factor: 0 (system default), 1, 2, 4 or 8")
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	if err := checkAlign(*af); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("invalid format %q", *format)
+	}
+	pkg, err := loadPackage(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	objs, err := lookupStructs(pkg, fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	layouts := make([]*alignbinary.StructLayout, len(objs))
+	for i, obj := range objs {
+		if layouts[i], err = typeLayout(obj, alignbinary.AlignFactor(*af)); err != nil {
+			return err
+		}
+	}
+	if *format == "json" {
+		return writeLayoutsJSON(w, layouts)
+	}
+	for i, sl := range layouts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if _, err = io.WriteString(w, sl.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkAlign returns an error if af isn't a valid alignment factor.
+func checkAlign(af uint) error {
+	if af > 8 || af&(af-1) != 0 {
+		return fmt.Errorf("invalid alignment factor %v", af)
+	}
+	return nil
+}
+
+type jsonStruct struct {
+	Name        string      `json:"name"`
+	Align       uint        `json:"alignFactor"`
+	Size        uintptr     `json:"size"`
+	Alignment   uintptr     `json:"alignment"`
+	TrailingPad uintptr     `json:"trailingPadding"`
+	Fields      []jsonField `json:"fields"`
+}
+
+type jsonField struct {
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	Type      string      `json:"type"`
+	Offset    uintptr     `json:"offset"`
+	Size      uintptr     `json:"size"`
+	Alignment uintptr     `json:"alignment"`
+	PadBefore uintptr     `json:"paddingBefore"`
+	PadAfter  uintptr     `json:"paddingAfter"`
+	Fields    []jsonField `json:"fields,omitempty"`
+}
+
+// writeLayoutsJSON writes the layouts into w as a JSON array.
+func writeLayoutsJSON(w io.Writer, layouts []*alignbinary.StructLayout) error {
+	out := make([]jsonStruct, len(layouts))
+	for i, sl := range layouts {
+		out[i] = jsonStruct{sl.TypeName, uint(sl.Factor), sl.Size, sl.Align, sl.TrailingPad, jsonFields(sl.Fields)}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func jsonFields(fields []alignbinary.FieldLayout) []jsonField {
+	if len(fields) == 0 {
+		return nil
+	}
+	out := make([]jsonField, len(fields))
+	for i, f := range fields {
+		out[i] = jsonField{f.Name, f.Path, f.TypeName, f.Offset, f.Size, f.Align, f.PadBefore, f.PadAfter, jsonFields(f.Fields)}
+	}
+	return out
+}
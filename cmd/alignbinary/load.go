@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+// loadPackage parses and type-checks the Go package in dir.
+// It returns the first error of type-checking, which has the position of the error,
+// the references of cgo to the package C are accepted without checking.
+func loadPackage(dir string) (*types.Package, error) {
+	bp, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(bp.GoFiles)+len(bp.CgoFiles))
+	for _, name := range append(bp.GoFiles, bp.CgoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	conf := types.Config{
+		Importer:    importer.ForCompiler(fset, "source", nil),
+		FakeImportC: true,
+	}
+	pkg, err := conf.Check(bp.ImportPath, fset, files, nil)
+	if err != nil {
+		return nil, err
+	}
+	return pkg, nil
+}
+
+// lookupStructs returns the named struct types in pkg with the given names,
+// or all of them in the order of names if no name is given.
+func lookupStructs(pkg *types.Package, names []string) ([]*types.TypeName, error) {
+	if len(names) == 0 {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			if obj, ok := scope.Lookup(name).(*types.TypeName); ok && isStruct(obj) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+	}
+	objs := make([]*types.TypeName, 0, len(names))
+	for _, name := range names {
+		obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+		if !ok || !isStruct(obj) {
+			return nil, fmt.Errorf("no struct type %v in package %v", name, pkg.Name())
+		}
+		objs = append(objs, obj)
+	}
+	return objs, nil
+}
+
+func isStruct(obj *types.TypeName) bool {
+	_, ok := obj.Type().Underlying().(*types.Struct)
+	return ok && !obj.IsAlias()
+}
+
+// typeLayout returns the layout of the named struct type obj based on the given af.
+// The names in the layout are the ones declared in the source.
+func typeLayout(obj *types.TypeName, af alignbinary.AlignFactor) (*alignbinary.StructLayout, error) {
+	t, err := reflectType(obj.Type(), obj.Name())
+	if err != nil {
+		return nil, err
+	}
+	sl, err := alignbinary.Layout(t, af)
+	if err != nil {
+		return nil, err
+	}
+	qf := types.RelativeTo(obj.Pkg())
+	sl.TypeName = obj.Name()
+	renameFields(sl.Fields, obj.Type().Underlying().(*types.Struct), "", qf)
+	return sl, nil
+}
+
+// reflectType returns a reflect.Type with the same layout as t, which is named by the path.
+// The fields of structs are named F0, F1, ... because reflect.StructOf only accepts
+// the exported names, use renameFields to restore the names of a layout.
+func reflectType(t types.Type, path string) (reflect.Type, error) {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		if rt, ok := basicTypes[u.Kind()]; ok {
+			return rt, nil
+		}
+	case *types.Array:
+		elem, err := reflectType(u.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return reflect.ArrayOf(int(u.Len()), elem), nil
+	case *types.Struct:
+		fields := make([]reflect.StructField, u.NumFields())
+		for i := range fields {
+			f := u.Field(i)
+			ft, err := reflectType(f.Type(), path+"."+f.Name())
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: ft}
+		}
+		return reflect.StructOf(fields), nil
+	}
+	return nil, fmt.Errorf("%v has invalid type %v", path, t)
+}
+
+// basicTypes maps the kinds of fixed-size basic types to the reflect types.
+var basicTypes = map[types.BasicKind]reflect.Type{
+	types.Bool:       reflect.TypeOf(false),
+	types.Int8:       reflect.TypeOf(int8(0)),
+	types.Uint8:      reflect.TypeOf(uint8(0)),
+	types.Int16:      reflect.TypeOf(int16(0)),
+	types.Uint16:     reflect.TypeOf(uint16(0)),
+	types.Int32:      reflect.TypeOf(int32(0)),
+	types.Uint32:     reflect.TypeOf(uint32(0)),
+	types.Int64:      reflect.TypeOf(int64(0)),
+	types.Uint64:     reflect.TypeOf(uint64(0)),
+	types.Float32:    reflect.TypeOf(float32(0)),
+	types.Float64:    reflect.TypeOf(float64(0)),
+	types.Complex64:  reflect.TypeOf(complex64(0)),
+	types.Complex128: reflect.TypeOf(complex128(0)),
+}
+
+// renameFields restores the names, paths and type names of the fields
+// of a layout converted by reflectType from the struct type st.
+func renameFields(fields []alignbinary.FieldLayout, st *types.Struct, path string, qf types.Qualifier) {
+	for i := range fields {
+		f := &fields[i]
+		v := st.Field(i)
+		f.Name = v.Name()
+		f.Path = f.Name
+		if path != "" {
+			f.Path = path + "." + f.Name
+		}
+		f.TypeName = types.TypeString(v.Type(), qf)
+		if len(f.Fields) > 0 {
+			renameFields(f.Fields, elemStruct(v.Type()), f.Path, qf)
+		}
+	}
+}
+
+// elemStruct returns the struct type of t, or of the elements if t is an array.
+func elemStruct(t types.Type) *types.Struct {
+	for {
+		switch u := t.Underlying().(type) {
+		case *types.Array:
+			t = u.Elem()
+		case *types.Struct:
+			return u
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Command alignbinary inspects and generates the binary layouts of Go types
+// encoded by the package alignbinary, without running the programs defining them.
+//
+// Usage:
+//
+//	alignbinary <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	layout    print the layouts of struct types in a package
+//	gen-c     generate a C header declaring struct types in a package
+//	gen-go    generate Go struct types from the structs in a C header
+//	gen-marshal
+//	          generate reflection-free encoding methods of struct types
+//	gen-view  generate views accessing the fields of encoded struct types in place
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is a subcommand of alignbinary.
+type command struct {
+	name  string
+	usage string
+	run   func(w io.Writer, args []string) error
+}
+
+var commands = []*command{
+	{"layout", layoutUsage, runLayout},
+	{"gen-c", genCUsage, runGenC},
+	{"gen-go", genGoUsage, runGenGo},
+	{"gen-marshal", genMarshalUsage, runGenMarshal},
+	{"gen-view", genViewUsage, runGenView},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	for _, cmd := range commands {
+		if cmd.name == os.Args[1] {
+			if err := cmd.run(os.Stdout, os.Args[2:]); err != nil {
+				if err == flag.ErrHelp {
+					os.Exit(2)
+				}
+				fmt.Fprintf(os.Stderr, "alignbinary %v: %v\n", cmd.name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\talignbinary %v\n", cmd.usage)
+	}
+	os.Exit(2)
+}
+
+// newFlagSet returns a flag set of the named command, whose usage prints the given usage line.
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: alignbinary %v\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLayoutText(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runLayout(buf, []string{"-align", "4", "testdata/msgs", "Msg"}); err != nil {
+		t.Fatalf("TestLayoutText: %v", err)
+	}
+	for _, want := range []string{
+		"type Msg struct {",
+		"        Seq   uint32     //      8     4     4\n",
+		"    Payload   [2]float64 //     12    16     4\n",
+		"// size: 32, alignment: 4, trailing padding: 0\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("TestLayoutText: have\n%v\nwant line %q", buf, want)
+		}
+	}
+}
+
+func TestLayoutJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runLayout(buf, []string{"-format", "json", "testdata/msgs"}); err != nil {
+		t.Fatalf("TestLayoutJSON: %v", err)
+	}
+	var have []jsonStruct
+	if err := json.Unmarshal(buf.Bytes(), &have); err != nil {
+		t.Fatalf("TestLayoutJSON: %v", err)
+	}
+	if len(have) != 3 || have[1].Name != "Msg" || have[1].Size != 40 || have[1].Fields[2].Offset != 16 {
+		t.Errorf("TestLayoutJSON: have %+v", have)
+	}
+}
+
+func TestLayoutTypeError(t *testing.T) {
+	dir := t.TempDir()
+	src := "package bad\n\ntype T struct {\n\tA Missing\n\tB int32\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "bad.go"), []byte(src), 0666); err != nil {
+		t.Fatal(err)
+	}
+	err := runLayout(&bytes.Buffer{}, []string{dir, "T"})
+	if want := "bad.go:4:4: undefined: Missing"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("TestLayoutTypeError: have error %v, want %q", err, want)
+	}
+}
+
+func TestGenC(t *testing.T) {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("TestGenC: no C compiler")
+	}
+	dir := t.TempDir()
+	for _, af := range []string{"0", "1", "2", "4", "8"} {
+		header := filepath.Join(dir, "msgs"+af+".h")
+		if err = runGenC(nil, []string{"-align", af, "-o", header, "testdata/msgs"}); err != nil {
+			t.Fatalf("TestGenC: %v", err)
+		}
+		src := filepath.Join(dir, "msgs"+af+".c")
+		os.WriteFile(src, []byte("#include \"msgs"+af+".h\"\n"), 0666)
+		// The static assertions fail the compilation if the layouts mismatch.
+		if out, err := exec.Command(cc, "-std=c11", "-fsyntax-only", src).CombinedOutput(); err != nil {
+			t.Errorf("TestGenC: align %v: %v\n%s", af, err, out)
+		}
+	}
+	// The C keywords are escaped, and the offsets of the fields of the anonymous
+	// structs are asserted too.
+	pkg := filepath.Join(dir, "keywords")
+	os.Mkdir(pkg, 0777)
+	os.WriteFile(filepath.Join(pkg, "keywords.go"), []byte(`package keywords
+
+type Keywords struct {
+	int  uint8
+	char [2]struct {
+		long  uint8
+		union uint32
+	}
+	bool bool
+}
+`), 0666)
+	header := filepath.Join(dir, "keywords.h")
+	if err = runGenC(nil, []string{"-o", header, pkg}); err != nil {
+		t.Fatalf("TestGenC: %v", err)
+	}
+	have, _ := os.ReadFile(header)
+	for _, want := range []string{
+		"\tuint8_t int_;\n",
+		"\t\tuint32_t union_;\n",
+		"\tbool bool_;\n",
+		"_Static_assert(offsetof(Keywords, char_[0].union_) == 8, \"offset of Keywords.char_[0].union_\");\n",
+	} {
+		if !strings.Contains(string(have), want) {
+			t.Errorf("TestGenC: have\n%s\nwant %q", have, want)
+		}
+	}
+	src := filepath.Join(dir, "keywords.c")
+	os.WriteFile(src, []byte("#include \"keywords.h\"\n"), 0666)
+	if out, err := exec.Command(cc, "-std=c11", "-fsyntax-only", src).CombinedOutput(); err != nil {
+		t.Errorf("TestGenC: %v\n%s", err, out)
+	}
+}
+
+func TestGenGo(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runGenGo(buf, []string{"-package", "sample", "testdata/sample.h"}); err != nil {
+		t.Fatalf("TestGenGo: %v", err)
+	}
+	// The offsets and sizes in the golden file are the ones compiled by gcc on amd64.
+	want, _ := os.ReadFile("testdata/sample.golden")
+	if buf.String() != string(want) {
+		t.Errorf("TestGenGo: have\n%v\nwant\n%s", buf, want)
+	}
+}
+
+func TestGenGoHeader(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runGenGo(buf, []string{"testdata/api.h"}); err != nil {
+		t.Fatalf("TestGenGoHeader: %v", err)
+	}
+	for _, want := range []string{
+		"package api\n",
+		"\tAPI_MID     = 4\n",
+		"\tAPI_MAX     = 10\n",
+		"\tAPI_ERR     = -1\n",
+		"// Api_event_t is the C struct of size 20, alignment 4.\n",
+		"\tLevel  int32   // offset 0\n",
+		"\tLabel  [8]int8 // offset 9\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("TestGenGoHeader: have\n%v\nwant line %q", buf, want)
+		}
+	}
+	if strings.Contains(buf.String(), "Api_ctx") {
+		t.Errorf("TestGenGoHeader: have the opaque struct in\n%v", buf)
+	}
+}
+
+// modulePath is the path of the module of the package alignbinary.
+const modulePath = "github.com/happyxcj/alignbinary"
+
+// tempModule returns a temporary module to build the generated code, which
+// requires a copy of the package alignbinary in the source tree as its module.
+// The module declares go 1.23 for the range over functions and the min, max
+// and clear builtins used by the package.
+func tempModule(t *testing.T) string {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Fatalf("%v: no go command: %v", t.Name(), err)
+	}
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "alignbinary")
+	gen := filepath.Join(dir, "gen")
+	for _, d := range []string{lib, gen} {
+		if err := os.Mkdir(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := filepath.Glob("../../*.go")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("%v: no source files of the package alignbinary: %v", t.Name(), err)
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(lib, filepath.Base(file)), src, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mods := map[string]string{
+		lib: fmt.Sprintf("module %v\n\ngo 1.23\n", modulePath),
+		gen: fmt.Sprintf("module gentest\n\ngo 1.23\n\nrequire %v v0.0.0\n\nreplace %[1]v => ../alignbinary\n", modulePath),
+	}
+	for d, mod := range mods {
+		if err = os.WriteFile(filepath.Join(d, "go.mod"), []byte(mod), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return gen
+}
+
+// goTest runs the tests of the packages in the temporary module dir, and
+// returns the verbose output.
+func goTest(t *testing.T, dir string) string {
+	cmd := exec.Command("go", "test", "-count=1", "-v", "./...")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v: %v\n%s", t.Name(), err, out)
+	}
+	return string(out)
+}
+
+func TestGenGoTest(t *testing.T) {
+	dir := tempModule(t)
+	for _, name := range []string{"sample", "api"} {
+		pkg := filepath.Join(dir, name)
+		os.Mkdir(pkg, 0777)
+		args := []string{"-o", filepath.Join(pkg, name+".go"), "-test", filepath.Join(pkg, name+"_test.go"), "testdata/" + name + ".h"}
+		if err := runGenGo(nil, args); err != nil {
+			t.Fatalf("TestGenGoTest: %v", err)
+		}
+	}
+	if out := goTest(t, dir); strings.Count(out, "--- PASS: TestCLayouts") != 2 {
+		t.Errorf("TestGenGoTest: have\n%v\nwant 2 passed TestCLayouts", out)
+	}
+}
+
+func TestGenMarshal(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runGenMarshal(buf, []string{"-align", "4", "-type", "Msg,Sample", "-o", "-", "testdata/msgs"}); err != nil {
+		t.Fatalf("TestGenMarshal: %v", err)
+	}
+	want, _ := os.ReadFile("testdata/msgs_aligned.golden")
+	if buf.String() != string(want) {
+		t.Errorf("TestGenMarshal: have\n%v\nwant\n%s", buf, want)
+	}
+	// The methods of the fixture of the package alignbinary are up to date,
+	// apart from the command line in the first line.
+	buf.Reset()
+	if err := runGenMarshal(buf, []string{"-align", "1", "-type", "Sequence", "-o", "-", "../../testdata/aligned"}); err != nil {
+		t.Fatalf("TestGenMarshal: %v", err)
+	}
+	want, _ = os.ReadFile("../../testdata/aligned/sequence_aligned.go")
+	_, have, _ := strings.Cut(buf.String(), "\n")
+	if _, want, _ := strings.Cut(string(want), "\n"); have != want {
+		t.Errorf("TestGenMarshal: have\n%v\nwant\n%v", have, want)
+	}
+	// The arrays longer than maxUnrolled are encoded by loops.
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "long.go"), []byte("package long\n\ntype Long struct {\n\tHdr uint8\n\tBody [20]uint16\n}\n"), 0666)
+	buf.Reset()
+	if err := runGenMarshal(buf, []string{"-align", "2", "-type", "Long", "-o", "-", dir}); err != nil {
+		t.Fatalf("TestGenMarshal: %v", err)
+	}
+	for _, want := range []string{"for i0 := 0; i0 < 20; i0++ {", "order.PutUint16(b[i0*2+2:], uint16(m.Body[i0]))"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("TestGenMarshal: have\n%v\nwant %q", buf, want)
+		}
+	}
+}
+
+// marshalTest is the test of the methods generated for the package msgs,
+// which must encode and decode as the reflection does.
+const marshalTest = `package msgs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+// plainSample is Sample without the methods, which is encoded by the reflection.
+type plainSample Sample
+
+func TestMarshalAligned(t *testing.T) {
+	m := Sample{Id: 1, Value: complex(2, 3)}
+	m.Tags[1].On, m.Tags[1].Val = true, -4
+	m.Msgs[1] = Msg{Kind: 5, Hdr: Hdr{[3]uint8{6, 7, 8}, 9}, Payload: [2]float64{10, 11}, CRC: 12}
+	af, size := m.AlignedLayout()
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		want, err := alignbinary.NewEncoderGroup(af).Encode(order, plainSample(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := m.MarshalAligned(order); len(have) != size || !bytes.Equal(have, want) {
+			t.Errorf("%v %v: have %v, want %v", af, order, have, want)
+		}
+		var have Sample
+		if err = have.UnmarshalAligned(want, order); err != nil || have != m {
+			t.Errorf("%v %v: have %+v, %v, want %+v", af, order, have, err, m)
+		}
+	}
+}
+`
+
+func TestGenMarshalBuild(t *testing.T) {
+	dir := tempModule(t)
+	src, err := os.ReadFile("testdata/msgs/msgs.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, af := range []string{"1", "2", "4", "8"} {
+		pkg := filepath.Join(dir, "msgs"+af)
+		os.Mkdir(pkg, 0777)
+		os.WriteFile(filepath.Join(pkg, "msgs.go"), src, 0666)
+		os.WriteFile(filepath.Join(pkg, "msgs_test.go"), []byte(marshalTest), 0666)
+		if err = runGenMarshal(nil, []string{"-align", af, "-type", "Msg,Sample", pkg}); err != nil {
+			t.Fatalf("TestGenMarshalBuild: %v", err)
+		}
+	}
+	if out := goTest(t, dir); strings.Count(out, "--- PASS: TestMarshalAligned") != 4 {
+		t.Errorf("TestGenMarshalBuild: have\n%v\nwant 4 passed TestMarshalAligned", out)
+	}
+	// The layouts of the system default depend on the platform.
+	if err = runGenMarshal(nil, []string{"-type", "Msg", "testdata/msgs"}); err == nil {
+		t.Errorf("TestGenMarshalBuild: have no error for AlignDefault")
+	}
+}
+
+func TestGenView(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := runGenView(buf, []string{"-align", "4", "-order", "big", "-type", "Sample", "-o", "-", "testdata/msgs"}); err != nil {
+		t.Fatalf("TestGenView: %v", err)
+	}
+	want, _ := os.ReadFile("testdata/sample_view.golden")
+	if buf.String() != string(want) {
+		t.Errorf("TestGenView: have\n%v\nwant\n%s", buf, want)
+	}
+	if err := runGenView(buf, []string{"-align", "4", "-order", "middle", "-type", "Sample", "testdata/msgs"}); err == nil {
+		t.Errorf("TestGenView: have no error for an invalid byte order")
+	}
+	if err := runGenView(buf, []string{"-type", "Sample", "testdata/msgs"}); err == nil {
+		t.Errorf("TestGenView: have no error for AlignDefault")
+	}
+}
+
+// viewTest is the test of the views generated for the package msgs with the
+// alignment factor and the byte order, whose accessors must match the Encode.
+const viewTest = `package msgs
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+func TestView(t *testing.T) {
+	m := Sample{Id: 1, Value: complex(2, 3)}
+	m.Tags[1].On, m.Tags[1].Val = true, -4
+	m.Msgs[1] = Msg{Kind: 5, Hdr: Hdr{[3]uint8{6, 7, 8}, 9}, Payload: [2]float64{10, 11}, CRC: 12}
+	eg, dg := alignbinary.NewEncoderGroup(%[1]v), alignbinary.NewDecoderGroup(%[1]v)
+	data, err := eg.Encode(binary.%[2]v, m)
+	if err != nil || len(data) != SampleViewSize {
+		t.Fatalf("have %%v bytes, %%v, want %%v bytes", len(data), err, SampleViewSize)
+	}
+	v := SampleView(data)
+	mv := v.Msgs(1)
+	if v.Id() != 1 || v.Value() != complex(2, 3) || v.Tags(0).On() || !v.Tags(1).On() || v.Tags(1).Val() != -4 ||
+		mv.Kind() != 5 || mv.Hdr().Flags(2) != 8 || mv.Hdr().Seq() != 9 || mv.Payload(1) != 11 || mv.CRC() != 12 {
+		t.Errorf("have the wrong fields of %%v", data)
+	}
+	v.SetId(13)
+	v.Tags(0).SetOn(true)
+	mv.Hdr().SetFlags(0, 14)
+	mv.SetPayload(0, 15)
+	mv.SetCRC(16)
+	m.Id, m.Tags[0].On, m.Msgs[1].Hdr.Flags[0], m.Msgs[1].Payload[0], m.Msgs[1].CRC = 13, true, 14, 15, 16
+	var have Sample
+	if err = dg.Decode(data, binary.%[2]v, &have); err != nil || have != m {
+		t.Errorf("have %%+v, %%v, want %%+v", have, err, m)
+	}
+}
+`
+
+func TestGenViewBuild(t *testing.T) {
+	dir := tempModule(t)
+	src, err := os.ReadFile("testdata/msgs/msgs.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, af := range []string{"1", "2", "4", "8"} {
+		for _, order := range []string{"little", "big"} {
+			pkg := filepath.Join(dir, "msgs"+af+order)
+			os.Mkdir(pkg, 0777)
+			os.WriteFile(filepath.Join(pkg, "msgs.go"), src, 0666)
+			test := fmt.Sprintf(viewTest, "alignbinary.Align"+af+"Byte", orderNames[order])
+			os.WriteFile(filepath.Join(pkg, "msgs_test.go"), []byte(test), 0666)
+			if err = runGenView(nil, []string{"-align", af, "-order", order, "-type", "Sample", pkg}); err != nil {
+				t.Fatalf("TestGenViewBuild: %v", err)
+			}
+		}
+	}
+	if out := goTest(t, dir); strings.Count(out, "--- PASS: TestView") != 8 {
+		t.Errorf("TestGenViewBuild: have\n%v\nwant 8 passed TestView", out)
+	}
+}
+
+func TestParseCErrors(t *testing.T) {
+	for _, src := range []string{
+		"struct s { int *p; };",
+		"struct s { int a : 3; };",
+		"struct s { char data[]; };",
+		"typedef struct t t2;",
+		"#pragma pack(pop)",
+		"#pragma pack(3)",
+		"enum e { A = B };",
+		"int f(void)",
+	} {
+		if _, err := parseC(src); err == nil {
+			t.Errorf("TestParseCErrors: have no error for %q", src)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+#ifndef API_H
+#define API_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define API_VERSION 3
+
+enum api_level {
+	API_LOW,
+	API_MID = 4,
+	API_HIGH,
+	API_MAX = API_HIGH << 1,
+};
+
+typedef enum { API_OK, API_ERR = -1 } api_status_t;
+
+/* The opaque handle and the callback are skipped. */
+typedef struct api_ctx *api_handle_t;
+typedef void (*api_callback_t)(void *arg, int status);
+
+typedef struct {
+	enum api_level level;
+	api_status_t status;
+	uint8_t code;
+	char label[(API_VERSION + 1) * 2];
+} api_event_t;
+
+extern const char *api_name;
+extern int api_count;
+
+api_handle_t api_open(const char *name, size_t len);
+int api_poll(api_handle_t h, api_event_t *ev, api_callback_t cb);
+void api_close(api_handle_t h);
+
+static inline int api_ok(const api_event_t *ev)
+{
+	if (ev->status != API_OK) {
+		return 0;
+	}
+	return 1;
+}
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif /* API_H */
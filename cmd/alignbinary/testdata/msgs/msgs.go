@@ -0,0 +1,24 @@
+package msgs
+
+type Hdr struct {
+	Flags [3]uint8
+	Seq   uint32
+}
+
+type Msg struct {
+	Kind    uint8
+	Hdr     Hdr
+	Payload [2]float64
+	_       uint8
+	CRC     uint16
+}
+
+type Sample struct {
+	Id    uint16
+	Value complex64
+	Tags  [2]struct {
+		On  bool
+		Val int64
+	}
+	Msgs [2]Msg
+}
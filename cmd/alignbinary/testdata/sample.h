@@ -0,0 +1,43 @@
+#ifndef SAMPLE_H
+#define SAMPLE_H
+
+#include <stdint.h>
+
+#define NAME_LEN 6
+#define NUM_CHANNELS (NAME_LEN * 2 + 1) /* 13 */
+#define FLAG_ON (1 << 3)
+
+typedef uint8_t name_t[NAME_LEN];
+
+struct point {
+	int16_t x, y;
+};
+
+typedef union {
+	uint32_t u;
+	float f;
+	unsigned char raw[5];
+} value_t;
+
+typedef struct {
+	unsigned char kind;
+	struct point pos;
+	long long stamp;
+	value_t value;
+	struct {
+		char on;
+		double gain;
+	} channels[2];
+	name_t name;
+} sample_t;
+
+#pragma pack(push, 2)
+typedef struct packed_s {
+	char tag;
+	uint32_t seq;
+	double values[NUM_CHANNELS];
+	short crc;
+} packed_t;
+#pragma pack(pop)
+
+#endif
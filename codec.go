@@ -0,0 +1,187 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// Profile is a preset of the alignment factor and the byte order
+// shared by the both sides of a protocol.
+type Profile struct {
+	Align AlignFactor
+	Order binary.ByteOrder
+}
+
+var (
+	// ProfileNative is the Go memory layout of the host with the native byte order:
+	// the AlignDefault, which is the layout of the package unsafe and may differ
+	// from the one of a C compiler.
+	ProfileNative = Profile{AlignDefault, binary.NativeEndian}
+	// ProfileNetwork is the layout of the package binary in the standard library
+	// with the network byte order: no padding and big endian.
+	ProfileNetwork = Profile{Align1Byte, binary.BigEndian}
+)
+
+// Codec binds an alignment factor, a byte order and the options together,
+// so a protocol can be defined once and passed around as a value.
+// It owns an EncoderGroup and a matching DecoderGroup.
+//
+// A Codec is safe for concurrent use by multiple goroutines.
+type Codec struct {
+	af     AlignFactor
+	order  binary.ByteOrder
+	strict bool
+	// parallelism is the number of goroutines encoding or decoding a large slice.
+	parallelism int
+	eg          *EncoderGroup
+	dg          *DecoderGroup
+}
+
+// CodecOption configures a Codec.
+type CodecOption func(c *Codec)
+
+// WithAlign sets the alignment factor, AlignDefault is used by default.
+func WithAlign(af AlignFactor) CodecOption {
+	return func(c *Codec) {
+		c.af = af
+	}
+}
+
+// WithOrder sets the byte order, the native byte order is used by default.
+func WithOrder(order binary.ByteOrder) CodecOption {
+	return func(c *Codec) {
+		c.order = order
+	}
+}
+
+// WithProfile sets both the alignment factor and the byte order of p.
+func WithProfile(p Profile) CodecOption {
+	return func(c *Codec) {
+		c.af = p.Align
+		c.order = p.Order
+	}
+}
+
+// WithStrict makes Unmarshal reject the data longer than the message
+// with a *TrailingDataError, the DecoderGroup is created WithExactLength.
+func WithStrict(strict bool) CodecOption {
+	return func(c *Codec) {
+		c.strict = strict
+	}
+}
+
+// WithParallelism makes Marshal and Unmarshal process a large slice in element-aligned
+// chunks by up to n goroutines, see WithEncodeParallelism and WithDecodeParallelism.
+func WithParallelism(n int) CodecOption {
+	return func(c *Codec) {
+		c.parallelism = n
+	}
+}
+
+// NewCodec returns a new Codec configured by the opts.
+// Without any option, it uses the ProfileNative.
+// It panics if the alignment factor is invalid.
+func NewCodec(opts ...CodecOption) *Codec {
+	c := &Codec{af: ProfileNative.Align, order: ProfileNative.Order, parallelism: 1}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.eg = NewEncoderGroup(c.af, WithEncodeParallelism(c.parallelism))
+	c.dg = NewDecoderGroup(c.af, WithExactLength(c.strict), WithDecodeParallelism(c.parallelism))
+	return c
+}
+
+// Align returns the alignment factor of c.
+func (c *Codec) Align() AlignFactor {
+	return c.af
+}
+
+// Order returns the byte order of c.
+func (c *Codec) Order() binary.ByteOrder {
+	return c.order
+}
+
+// EncoderGroup returns the encoder group owned by c.
+func (c *Codec) EncoderGroup() *EncoderGroup {
+	return c.eg
+}
+
+// DecoderGroup returns the decoder group owned by c.
+func (c *Codec) DecoderGroup() *DecoderGroup {
+	return c.dg
+}
+
+// Marshal returns the binary representation of msg.
+// See EncoderGroup.Encode for the supported msg.
+func (c *Codec) Marshal(msg interface{}) ([]byte, error) {
+	return c.eg.Encode(c.order, msg)
+}
+
+// Unmarshal decodes the data into msg.
+// See DecoderGroup.Decode for the supported msg.
+func (c *Codec) Unmarshal(data []byte, msg interface{}) error {
+	return c.dg.Decode(data, c.order, msg)
+}
+
+// EncodeToPointer encodes msg into the n bytes of memory at p.
+// See EncoderGroup.EncodeToPointer for details.
+func (c *Codec) EncodeToPointer(p unsafe.Pointer, n int, msg interface{}) (int, error) {
+	return c.eg.EncodeToPointer(p, n, c.order, msg)
+}
+
+// DecodeFromPointer decodes msg from the n bytes of memory at p.
+// See DecoderGroup.DecodeFromPointer for details.
+func (c *Codec) DecodeFromPointer(p unsafe.Pointer, n int, msg interface{}) error {
+	return c.dg.DecodeFromPointer(p, n, c.order, msg)
+}
+
+// Write writes the binary representation of msg into w.
+func (c *Codec) Write(w io.Writer, msg interface{}) error {
+	return c.eg.Write(w, c.order, msg)
+}
+
+// Read reads the binary representation of msg from r and decodes it.
+func (c *Codec) Read(r io.Reader, msg interface{}) error {
+	return c.dg.Read(r, c.order, msg)
+}
+
+// Get decodes the field referred by the path from the data encoded from a struct of type t.
+// See DecoderGroup.Get for the path.
+func (c *Codec) Get(data []byte, t reflect.Type, path string) (interface{}, error) {
+	return c.dg.Get(data, c.order, t, path)
+}
+
+// Set encodes the value into the field referred by the path within the data
+// encoded from a struct of type t. See EncoderGroup.Set for the path.
+func (c *Codec) Set(data []byte, t reflect.Type, path string, value interface{}) error {
+	return c.eg.Set(data, c.order, t, path, value)
+}
+
+// Dump writes an annotated hex dump of the data encoded from a value of the struct type t.
+// See DecoderGroup.Dump for the format.
+func (c *Codec) Dump(w io.Writer, data []byte, t reflect.Type) error {
+	return c.dg.Dump(w, data, c.order, t)
+}
+
+// Size returns the number of bytes of the binary representation of msg.
+// Msg must be a fixed-size value, a pointer to a fixed-size value,
+// or a slice of fixed-size values.
+func (c *Codec) Size(msg interface{}) int {
+	if _, size := c.eg.assertMsg(msg); size != -1 {
+		return size
+	}
+	_, _, size := c.eg.reflectMsg(msg)
+	return size
+}
+
+// NewEncoder returns a new StreamEncoder that writes into w with c.
+func (c *Codec) NewEncoder(w io.Writer) *StreamEncoder {
+	return NewStreamEncoder(w, c.order, c.eg)
+}
+
+// NewDecoder returns a new StreamDecoder that reads from r with c.
+func (c *Codec) NewDecoder(r io.Reader) *StreamDecoder {
+	return NewStreamDecoder(r, c.order, c.dg)
+}
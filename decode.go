@@ -11,16 +11,57 @@ import (
 
 type DecoderGroup struct {
 	af       AlignFactor
+	// exact makes Decode reject the data longer than the message.
+	exact bool
+	// maxRecords is the maximum number of elements read by ReadAll, 0 means no limit.
+	maxRecords  int
+	// parallelism is the number of goroutines decoding a large slice, 1 means sequential.
+	parallelism int
 	structInfos sync.Map
 	ptrInfo     decodePtrInfo
 	msgInfo     decodeMsgInfo
+	// paths caches the fields resolved by Get.
+	paths pathCache
 }
 
-func NewDecoderGroup(af AlignFactor) *DecoderGroup {
+// DecoderOption configures a DecoderGroup.
+type DecoderOption func(dg *DecoderGroup)
+
+// WithExactLength makes Decode and DecodeSequence behave like DecodeExact,
+// they return a *TrailingDataError if the data is longer than the message.
+func WithExactLength(exact bool) DecoderOption {
+	return func(dg *DecoderGroup) {
+		dg.exact = exact
+	}
+}
+
+// WithMaxRecords limits the number of elements ReadAll reads to n,
+// to protect against huge inputs. There's no limit if n is 0, the default.
+func WithMaxRecords(n int) DecoderOption {
+	return func(dg *DecoderGroup) {
+		dg.maxRecords = n
+	}
+}
+
+// WithDecodeParallelism makes Decode, DecodeAll and DecodeFromPointer decode a slice
+// larger than parallelThreshold bytes per goroutine in element-aligned chunks
+// by up to n goroutines. It's sequential if n is 1, the default.
+func WithDecodeParallelism(n int) DecoderOption {
+	return func(dg *DecoderGroup) {
+		dg.parallelism = n
+	}
+}
+
+func NewDecoderGroup(af AlignFactor, opts ...DecoderOption) *DecoderGroup {
 	checkAlignFactor(af)
-	return &DecoderGroup{
-		af: af,
+	dg := &DecoderGroup{
+		af:          af,
+		parallelism: 1,
 	}
+	for _, opt := range opts {
+		opt(dg)
+	}
+	return dg
 }
 
 // Read reads structured binary data from r into msg.
@@ -33,24 +74,57 @@ func NewDecoderGroup(af AlignFactor) *DecoderGroup {
 //
 // When decoding into structs, the field data for unexported fields or
 // fields with blank (_) field names is skipped.
+//
+// A slice or an array larger than chunkSize bytes is read and decoded
+// in chunks of whole elements through a reused buffer, so the memory use
+// doesn't grow with the size of msg.
+//
+// It returns io.EOF if no bytes are read, otherwise a *DecodeError
+// wrapping io.ErrUnexpectedEOF or the error of r.
 func (dg *DecoderGroup) Read(r io.Reader, order binary.ByteOrder, msg interface{}) error {
-	if decoder, size := dg.assertMsg(msg); size != -1 {
-		// Fast path for a pointer to a basic type value, or a slice of basic type values.
-		buf := make([]byte, size)
-		if _, err := io.ReadFull(r, buf); err != nil {
-			return err
+	_, _, err := dg.read(r, order, msg, nil)
+	return err
+}
+
+// read is like Read but reads the data of msg using buf as the scratch buffer.
+// It returns the scratch buffer, which is reallocated if buf is too small,
+// and the number of bytes read from r.
+func (dg *DecoderGroup) read(r io.Reader, order binary.ByteOrder, msg interface{}, buf []byte) ([]byte, int, error) {
+	if decoder, size := dg.assertMsg(msg); size != -1 && size <= chunkSize {
+		// Fast path for a pointer to a basic type value, or a small slice of basic type values.
+		buf = grow(buf, size)
+		n, err := io.ReadFull(r, buf)
+		if err != nil {
+			return buf, n, dg.readError(msg, size, n, err)
 		}
 		decoder(msg, buf, order)
-		return nil
+		return buf, n, nil
+	}
+	if m, size := assertUnmarshaler(msg, dg.af); size != -1 {
+		// Fast path for a type with the generated methods.
+		buf = grow(buf, size)
+		n, err := io.ReadFull(r, buf)
+		if err != nil {
+			return buf, n, dg.readError(msg, size, n, err)
+		}
+		return buf, n, m.UnmarshalAligned(buf, order)
+	}
+	if ptr, info := dg.reflectList(msg); info != nil {
+		buf, n, err := info.read(r, ptr, buf, order)
+		if err != nil {
+			err = dg.readError(msg, info.num*info.eleSize, n, err)
+		}
+		return buf, n, err
 	}
 	// Decode by reflecting the msg.
 	ptr, decoder, size := dg.reflectMsg(msg)
-	buf := make([]byte, size)
-	if _, err := io.ReadFull(r, buf); err != nil {
-		return err
+	buf = grow(buf, size)
+	n, err := io.ReadFull(r, buf)
+	if err != nil {
+		return buf, n, dg.readError(msg, size, n, err)
 	}
 	decoder(ptr, buf, order)
-	return nil
+	return buf, n, nil
 }
 
 // Decode decodes the msg using the specified byte order and 
@@ -63,24 +137,160 @@ func (dg *DecoderGroup) Read(r io.Reader, order binary.ByteOrder, msg interface{
 //
 // When decoding into structs, the field data for unexported fields or
 // fields with blank (_) field names is skipped.
+//
+// If msg implements AlignedUnmarshaler for the alignment factor of dg,
+// the generated methods are used instead of the reflection.
+//
+// It returns a *DecodeError wrapping io.ErrUnexpectedEOF if data is too short.
+// The bytes after the message are ignored, unless dg is created WithExactLength.
 func (dg *DecoderGroup) Decode(data []byte, order binary.ByteOrder, msg interface{}) error {
+	return dg.decode(data, order, msg, dg.exact)
+}
+
+// DecodeExact is like Decode but returns a *TrailingDataError
+// if data is longer than the message.
+func (dg *DecoderGroup) DecodeExact(data []byte, order binary.ByteOrder, msg interface{}) error {
+	return dg.decode(data, order, msg, true)
+}
+
+// decode is like Decode, it rejects the data longer than the message if exact is true.
+func (dg *DecoderGroup) decode(data []byte, order binary.ByteOrder, msg interface{}, exact bool) error {
 	if decoder, size := dg.assertMsg(msg); size != -1 {
 		// Fast path for a pointer to a basic type value, or a slice of basic type values.
-		if len(data) < size {
-			return io.ErrUnexpectedEOF
+		if err := dg.checkLen(msg, size, len(data), exact); err != nil {
+			return err
 		}
 		decoder(msg, data, order)
 		return nil
 	}
+	if m, size := assertUnmarshaler(msg, dg.af); size != -1 {
+		// Fast path for a type with the generated methods.
+		if err := dg.checkLen(msg, size, len(data), exact); err != nil {
+			return err
+		}
+		return m.UnmarshalAligned(data, order)
+	}
 	// Decode by reflecting the msg.
 	ptr, decoder, size := dg.reflectMsg(msg)
-	if len(data) < size {
-		return io.ErrUnexpectedEOF
+	if err := dg.checkLen(msg, size, len(data), exact); err != nil {
+		return err
 	}
 	decoder(ptr, data, order)
 	return nil
 }
 
+// checkLen returns an error if the have bytes of data are not enough
+// for msg of the given size, or more than it if exact is true.
+func (dg *DecoderGroup) checkLen(msg interface{}, size, have int, exact bool) error {
+	switch {
+	case have < size:
+		return dg.decodeError(msg, size, have, io.ErrUnexpectedEOF)
+	case exact && have > size:
+		return newTrailingDataError(msg, size, have)
+	}
+	return nil
+}
+
+// DecodeAll decodes as many whole elements as data contains into the slice
+// pointed by ptr, which must be a pointer to a slice of fixed-size values.
+// The slice is resized to the number of elements, its backing array is reused
+// if the capacity is enough.
+//
+// If data ends with a partial element, the whole elements are still decoded,
+// and a *DecodeError wrapping io.ErrUnexpectedEOF reports the partial one.
+func (dg *DecoderGroup) DecodeAll(data []byte, order binary.ByteOrder, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		panic(fmt.Sprintf("alignbinary: call DecodeAll on invalid type %T", ptr))
+	}
+	s := v.Elem()
+	elemSize := typeSize(s.Type().Elem(), dg.af)
+	n := 0
+	if elemSize > 0 {
+		n = len(data) / int(elemSize)
+	}
+	if s.Cap() >= n {
+		s.SetLen(n)
+		// Clear the skipped fields of the reused elements.
+		s.Clear()
+	} else {
+		s.Set(reflect.MakeSlice(s.Type(), n, n))
+	}
+	size := n * int(elemSize)
+	if err := dg.decode(data[:size], order, s.Interface(), false); err != nil {
+		return err
+	}
+	if len(data) > size {
+		return dg.decodeError(s.Interface(), size+int(elemSize), len(data), io.ErrUnexpectedEOF)
+	}
+	return nil
+}
+
+// msgSize returns the size of the binary representation of msg.
+func (dg *DecoderGroup) msgSize(msg interface{}) int {
+	if _, size := dg.assertMsg(msg); size != -1 {
+		return size
+	}
+	_, _, size := dg.reflectMsg(msg)
+	return size
+}
+
+// ReadAll reads back-to-back elements from r until io.EOF into the slice pointed
+// by ptr, which must be a pointer to a slice of fixed-size values.
+// The slice grows as the elements arrive, starting from the length 0
+// with its backing array reused.
+//
+// It returns nil at io.EOF after a whole element. If the final element is partial,
+// or reading fails, the whole elements are kept and a *DecodeError is returned,
+// whose FieldPath starts with the index of the failed element.
+// If dg is created WithMaxRecords, it returns ErrTooManyRecords after reading
+// the maximum number of elements if r has more data.
+func (dg *DecoderGroup) ReadAll(r io.Reader, order binary.ByteOrder, ptr interface{}) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+		panic(fmt.Sprintf("alignbinary: call ReadAll on invalid type %T", ptr))
+	}
+	s := v.Elem()
+	elemSize := typeSize(s.Type().Elem(), dg.af)
+	if elemSize == 0 {
+		panic(fmt.Sprintf("alignbinary: call ReadAll on zero-size elements %T", ptr))
+	}
+	eleSize := int(elemSize)
+	s.SetLen(0)
+	var buf []byte
+	for {
+		n := chunkLen(chunkSize, eleSize)
+		if dg.maxRecords > 0 && n > dg.maxRecords+1-s.Len() {
+			// Read one more element than the limit to detect the excess.
+			n = dg.maxRecords + 1 - s.Len()
+		}
+		buf = grow(buf, n*eleSize)
+		m, err := io.ReadFull(r, buf)
+		if whole := m / eleSize; whole > 0 {
+			i := s.Len()
+			if s.Cap() < i+whole {
+				s.Grow(whole)
+			}
+			s.SetLen(i + whole)
+			elems := s.Slice(i, i+whole)
+			elems.Clear()
+			dg.decode(buf[:whole*eleSize], order, elems.Interface(), false)
+		}
+		if dg.maxRecords > 0 && s.Len() > dg.maxRecords {
+			s.SetLen(dg.maxRecords)
+			return ErrTooManyRecords
+		}
+		switch {
+		case err == nil:
+			continue
+		case err == io.EOF || err == io.ErrUnexpectedEOF && m%eleSize == 0:
+			return nil
+		}
+		have := s.Len()*eleSize + m%eleSize
+		return dg.decodeError(s.Interface(), (s.Len()+1)*eleSize, have, err)
+	}
+}
+
 // assertMsg returns the message decoder and size by asserting the given msg.
 // The type of msg must be a basic type pointer, or a basic type slice,
 // if not, return nil and -1.
@@ -160,6 +370,11 @@ func (dg *DecoderGroup) reflectMsg(msg interface{}) (unsafe.Pointer, ptrDecoder,
 		info := new(decodeListInfo)
 		info.init(v, dg)
 		decoder, size = info.decode, info.num*info.eleSize
+		if workers := parallelWorkers(size, dg.parallelism); workers > 1 {
+			decoder = func(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
+				info.decodeParallel(ptr, buf, order, workers)
+			}
+		}
 	} else {
 		if kind != reflect.Ptr {
 			panic(fmt.Sprintf("alignbinary: call reflectMsg on invalid kind %v when decoding", kind))
@@ -169,6 +384,26 @@ func (dg *DecoderGroup) reflectMsg(msg interface{}) (unsafe.Pointer, ptrDecoder,
 	return unsafe.Pointer(v.Pointer()), decoder, size
 }
 
+// reflectList returns the pointer to the first element and the information to decode
+// the elements if msg is a slice or a pointer to an array, otherwise nil and nil.
+func (dg *DecoderGroup) reflectList(msg interface{}) (unsafe.Pointer, *decodeListInfo) {
+	v := reflect.ValueOf(msg)
+	switch v.Kind() {
+	case reflect.Slice:
+		info := new(decodeListInfo)
+		info.init(v, dg)
+		return unsafe.Pointer(v.Pointer()), info
+	case reflect.Ptr:
+		if v.Elem().Kind() != reflect.Array {
+			return nil, nil
+		}
+		info := new(decodeListInfo)
+		info.init(v.Elem(), dg)
+		return unsafe.Pointer(v.Pointer()), info
+	}
+	return nil, nil
+}
+
 // typePtrDecoder returns the size and decoder based on the given t under the align.
 // It panics if t's Kind is not Array, Struct, Bool,
 // Int8, Uint8, Int16, Uint16, Int32, Uint32, Int64, Uint64,
@@ -181,7 +416,7 @@ func (dg *DecoderGroup) typePtrDecoder(v reflect.Value) (ptrDecoder, int) {
 		return info.decode, info.num * info.eleSize
 	case reflect.Struct:
 		info := dg.getDecodeStructInfo(v)
-		return info.decode, info.size
+		return info.decoder, info.size
 	case reflect.Bool:
 		return dg.ptrInfo.bool, 1
 	case reflect.Int8:
@@ -2,6 +2,7 @@ package alignbinary
 
 import (
 	"encoding/binary"
+	"io"
 	"reflect"
 	"unsafe"
 )
@@ -13,7 +14,10 @@ type decodeListInfo struct {
 	// num is the number of all elements.
 	num int
 	// eleSize is the size of an element.
-	eleSize    int
+	eleSize int
+	// eleStride is the distance in memory between two successive elements,
+	// which differs from the eleSize when the alignment factor is not AlignDefault.
+	eleStride  uintptr
 	eleDecoder ptrDecoder
 }
 
@@ -23,23 +27,64 @@ func (li *decodeListInfo) init(v reflect.Value, dg *DecoderGroup) {
 	if li.num == 0 {
 		return
 	}
+	li.eleStride = v.Type().Elem().Size()
 	li.eleDecoder, li.eleSize = dg.typePtrDecoder(v.Index(0))
 }
 
 func (li *decodeListInfo) decode(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
+	li.decodeRange(ptr, 0, li.num, buf, order)
+}
+
+// decodeRange decodes the elements in the range [i, j) from the buf,
+// the first of them is decoded from the start of the buf.
+func (li *decodeListInfo) decodeRange(ptr unsafe.Pointer, i, j int, buf []byte, order binary.ByteOrder) {
 	var elePtr unsafe.Pointer
-	var offset uintptr
-	for i := 0; i < li.num; i++ {
-		offset = uintptr(i * li.eleSize)
-		elePtr = offsetPtr(ptr, offset)
-		li.eleDecoder(elePtr, buf[offset:], order)
+	for k := i; k < j; k++ {
+		elePtr = offsetPtr(ptr, uintptr(k)*li.eleStride)
+		li.eleDecoder(elePtr, buf[(k-i)*li.eleSize:], order)
+	}
+}
+
+// decodeParallel is like decode but decodes the elements in element-aligned chunks
+// by the given number of goroutines.
+func (li *decodeListInfo) decodeParallel(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder, workers int) {
+	parallelRange(li.num, workers, func(i, j int) {
+		li.decodeRange(ptr, i, j, buf[i*li.eleSize:], order)
+	})
+}
+
+// read reads the elements in chunks from r and decodes them,
+// ptr points to the first element and buf is the scratch buffer.
+// It returns the scratch buffer and the number of bytes read.
+func (li *decodeListInfo) read(r io.Reader, ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) ([]byte, int, error) {
+	n := chunkLen(li.num, li.eleSize)
+	buf = grow(buf, n*li.eleSize)
+	var read int
+	for i := 0; i < li.num; i += n {
+		j := i + n
+		if j > li.num {
+			j = li.num
+		}
+		data := buf[:(j-i)*li.eleSize]
+		m, err := io.ReadFull(r, data)
+		read += m
+		if err != nil {
+			if err == io.EOF && i > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return buf, read, err
+		}
+		li.decodeRange(ptr, i, j, data, order)
 	}
+	return buf, read, nil
 }
 
 type decodeStructInfo struct {
 	// size is the size of the struct.
 	size   int
 	fields []*decodeFieldInfo
+	// decoder is the method value of decode bound once, as binding it allocates.
+	decoder ptrDecoder
 }
 
 type decodeFieldInfo struct {
@@ -68,6 +113,7 @@ func (si *decodeStructInfo) init(v reflect.Value,dg *DecoderGroup) {
 	}
 	si.fields = fields
 	si.size = int(st.size)
+	si.decoder = si.decode
 }
 
 func (si *decodeStructInfo) decode(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
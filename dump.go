@@ -0,0 +1,162 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"text/tabwriter"
+)
+
+// dumpLineBytes is the number of bytes in a line of the hex column.
+const dumpLineBytes = 16
+
+// dumper writes the rows of an annotated hex dump.
+type dumper struct {
+	dg    *DecoderGroup
+	tw    *tabwriter.Writer
+	data  []byte
+	order binary.ByteOrder
+	// truncated reports whether the data ended within a field.
+	truncated bool
+}
+
+// Dump writes an annotated hex dump of the data encoded from a value of the struct type t.
+// Each row shows the path of a field, its offset range [start, end), the raw bytes
+// and the decoded value. The padding bytes are flagged if they're not zero.
+//
+// If data is too short, the dump stops at the first field which is not complete
+// and Dump returns a *DecodeError wrapping io.ErrUnexpectedEOF.
+func (dg *DecoderGroup) Dump(w io.Writer, data []byte, order binary.ByteOrder, t reflect.Type) error {
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("alignbinary: dump of non-struct type %v", t)
+	}
+	if err := checkType(t, t.Name()); err != nil {
+		return err
+	}
+	size := typeSize(t, dg.af)
+	d := &dumper{dg: dg, tw: tabwriter.NewWriter(w, 0, 4, 2, ' ', 0), data: data, order: order}
+	fmt.Fprintf(d.tw, "// %v: %v bytes, alignment factor %v, %v bytes of data\n", t, size, dg.af, len(data))
+	d.dumpStruct("", t, 0)
+	switch {
+	case d.truncated:
+	case len(data) < int(size):
+		// The data ends within the trailing padding.
+		d.truncated = true
+		fmt.Fprintf(d.tw, "// !! truncated: need %v bytes, have %v\n", size, len(data))
+	case len(data) > int(size):
+		fmt.Fprintf(d.tw, "// %v bytes of trailing data\n", len(data)-int(size))
+	}
+	if err := d.tw.Flush(); err != nil {
+		return err
+	}
+	if d.truncated {
+		path, offset := locateField(t, dg.af, 0, len(data))
+		return &DecodeError{Offset: int64(offset), Need: int(size), Have: len(data), FieldPath: path, Type: t, Err: io.ErrUnexpectedEOF}
+	}
+	return nil
+}
+
+// dumpStruct writes the rows of the fields of the struct type t
+// named by the path at the offset.
+func (d *dumper) dumpStruct(path string, t reflect.Type, offset int) {
+	st := structTyp{}
+	st.init(t, d.dg.af)
+	end := offset
+	for i := 0; i < t.NumField() && !d.truncated; i++ {
+		f := t.Field(i)
+		start := offset + int(st.fields[i])
+		d.dumpPadding(end, start)
+		name := f.Name
+		if path != "" {
+			name = path + "." + f.Name
+		}
+		d.dumpValue(name, f.Type, start)
+		size := typeSize(f.Type, d.dg.af)
+		end = start + int(size)
+	}
+	if !d.truncated {
+		d.dumpPadding(end, offset+int(st.size))
+	}
+}
+
+// dumpValue writes the rows of the value of type t named by the path at the offset.
+// The structs and the arrays containing structs are expanded.
+func (d *dumper) dumpValue(path string, t reflect.Type, offset int) {
+	switch {
+	case t.Kind() == reflect.Struct:
+		d.dumpStruct(path, t, offset)
+		return
+	case t.Kind() == reflect.Array && hasStruct(t):
+		elemSize := typeSize(t.Elem(), d.dg.af)
+		for i := 0; i < t.Len() && !d.truncated; i++ {
+			d.dumpValue(fmt.Sprintf("%v[%v]", path, i), t.Elem(), offset+i*int(elemSize))
+		}
+		return
+	}
+	size := typeSize(t, d.dg.af)
+	end := offset + int(size)
+	if end > len(d.data) {
+		d.truncated = true
+		var raw []byte
+		if offset < len(d.data) {
+			raw = d.data[offset:]
+		}
+		d.dumpRow(path, offset, end, raw, fmt.Sprintf("!! truncated: need %v bytes, have %v", size, len(raw)))
+		return
+	}
+	v := reflect.New(t)
+	decoder, _ := d.dg.bindMsg(v.Interface(), d.order)
+	decoder(d.data[offset:])
+	d.dumpRow(path, offset, end, d.data[offset:end], fmt.Sprint(v.Elem().Interface()))
+}
+
+// dumpPadding writes a row of the padding bytes in [start, end) if there are any.
+func (d *dumper) dumpPadding(start, end int) {
+	if start >= end {
+		return
+	}
+	if end > len(d.data) {
+		// The missing padding bytes don't stop decoding.
+		if start >= len(d.data) {
+			return
+		}
+		end = len(d.data)
+	}
+	note := "zero"
+	for _, b := range d.data[start:end] {
+		if b != 0 {
+			note = "!! non-zero padding"
+			break
+		}
+	}
+	d.dumpRow("(padding)", start, end, d.data[start:end], note)
+}
+
+// dumpRow writes a row, the hex column is wrapped into lines of dumpLineBytes.
+func (d *dumper) dumpRow(path string, start, end int, raw []byte, note string) {
+	fmt.Fprintf(d.tw, "[%v, %v)\t%v\t%v\t%v\n", start, end, path, hexLine(raw), note)
+	for len(raw) > dumpLineBytes {
+		raw = raw[dumpLineBytes:]
+		fmt.Fprintf(d.tw, "\t\t%v\t\n", hexLine(raw))
+	}
+}
+
+// hexLine returns the hex of the first dumpLineBytes bytes of b separated by spaces.
+func hexLine(b []byte) string {
+	switch {
+	case len(b) == 0:
+		return "-"
+	case len(b) > dumpLineBytes:
+		b = b[:dumpLineBytes]
+	}
+	return fmt.Sprintf("% x", b)
+}
+
+// hasStruct reports whether the array type t contains structs.
+func hasStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
@@ -11,28 +11,79 @@ import (
 
 type EncoderGroup struct {
 	af AlignFactor
+	// parallelism is the number of goroutines encoding a large slice, 1 means sequential.
+	parallelism int
 	structInfos sync.Map
 	ptrInfo     encodePtrInfo
 	msgInfo     encodeMsgInfo
+	// paths caches the fields resolved by Set.
+	paths pathCache
 }
 
-func NewEncoderGroup(af AlignFactor) *EncoderGroup {
+// EncoderOption configures an EncoderGroup.
+type EncoderOption func(eg *EncoderGroup)
+
+// WithEncodeParallelism makes Encode and EncodeToPointer encode a slice larger than
+// parallelThreshold bytes per goroutine in element-aligned chunks by up to n goroutines.
+// The output is identical to the sequential one. It's sequential if n is 1, the default.
+func WithEncodeParallelism(n int) EncoderOption {
+	return func(eg *EncoderGroup) {
+		eg.parallelism = n
+	}
+}
+
+func NewEncoderGroup(af AlignFactor, opts ...EncoderOption) *EncoderGroup {
 
 	checkAlignFactor(af)
 
-	return &EncoderGroup{
-		af: af,
+	eg := &EncoderGroup{
+		af:          af,
+		parallelism: 1,
 	}
+	for _, opt := range opts {
+		opt(eg)
+	}
+	return eg
 }
 
 // Encode writes the binary representation of msg into w.
 // It can be called like 'binary.Write'
+//
+// A slice or an array larger than chunkSize bytes is encoded and written
+// in chunks of whole elements through a reused buffer, so the memory use
+// doesn't grow with the size of msg.
 func (eg *EncoderGroup) Write(w io.Writer, order binary.ByteOrder, msg interface{}) error {
-	data, _ := defaultEG.Encode(order, msg)
-	_, err := w.Write(data)
+	_, _, err := eg.write(w, order, msg, nil)
 	return err
 }
 
+// write is like Write but encodes msg using buf as the scratch buffer.
+// It returns the scratch buffer, which is reallocated if buf is too small,
+// and the number of bytes written into w.
+func (eg *EncoderGroup) write(w io.Writer, order binary.ByteOrder, msg interface{}, buf []byte) ([]byte, int, error) {
+	if encoder, size := eg.assertMsg(msg); size != -1 && size <= chunkSize {
+		// Fast path for a basic type value, or a small slice of basic type values.
+		buf = grow(buf, size)
+		encoder(msg, buf, order)
+		n, err := w.Write(buf)
+		return buf, n, err
+	}
+	if m, size := assertMarshaler(msg, eg.af); size != -1 {
+		// Fast path for a type with the generated methods.
+		buf = m.AppendAligned(buf[:0], order)
+		n, err := w.Write(buf)
+		return buf, n, err
+	}
+	if ptr, info := eg.reflectList(msg); info != nil {
+		return info.write(w, ptr, buf, order)
+	}
+	ptr, encoder, size := eg.reflectMsg(msg)
+	buf = grow(buf, size)
+	encoder(ptr, buf, order)
+	n, err := w.Write(buf)
+	return buf, n, err
+}
+
 // Encode encodes the msg and returns the binary representation of msg.
 //
 // Msg must be a fixed-size value, a pointer to a fixed-size value,
@@ -45,6 +96,9 @@ func (eg *EncoderGroup) Write(w io.Writer, order binary.ByteOrder, msg interface
 //
 // When encoding structs, zero values are encoded for unexported fields or
 // fields with blank (_) field names.
+//
+// If msg implements AlignedMarshaler for the alignment factor of eg,
+// the generated methods are used instead of the reflection.
 func (eg *EncoderGroup) Encode(order binary.ByteOrder, msg interface{}) ([]byte, error) {
 	if encoder, size := eg.assertMsg(msg); size != -1 {
 		// Fast path for a basic type value, or a slice of basic type values.
@@ -52,6 +106,10 @@ func (eg *EncoderGroup) Encode(order binary.ByteOrder, msg interface{}) ([]byte,
 		encoder(msg, buf, order)
 		return buf, nil
 	}
+	if m, size := assertMarshaler(msg, eg.af); size != -1 {
+		// Fast path for a type with the generated methods.
+		return m.AppendAligned(make([]byte, 0, size), order), nil
+	}
 	// Encode by reflecting the msg.
 	ptr, encoder, size := eg.reflectMsg(msg)
 	buf := make([]byte, size)
@@ -167,6 +225,11 @@ func (eg *EncoderGroup) reflectMsg(msg interface{}) (unsafe.Pointer, ptrEncoder,
 		info := new(encodeListInfo)
 		info.init(v, eg)
 		encoder, size = info.encode, info.eleSize*info.num
+		if workers := parallelWorkers(size, eg.parallelism); workers > 1 {
+			encoder = func(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
+				info.encodeParallel(ptr, buf, order, workers)
+			}
+		}
 	} else {
 		if kind != reflect.Ptr {
 			// Convert to a pointer that points to the data of msg interface.
@@ -179,6 +242,33 @@ func (eg *EncoderGroup) reflectMsg(msg interface{}) (unsafe.Pointer, ptrEncoder,
 	return unsafe.Pointer(v.Pointer()), encoder, size
 }
 
+// reflectList returns the pointer to the first element and the information to encode
+// the elements if msg is a slice, an array or a pointer to an array, otherwise nil and nil.
+func (eg *EncoderGroup) reflectList(msg interface{}) (unsafe.Pointer, *encodeListInfo) {
+	v := reflect.ValueOf(msg)
+	switch v.Kind() {
+	case reflect.Slice:
+	case reflect.Array:
+		// Convert to a pointer that points to the data of msg interface.
+		u := reflect.New(v.Type())
+		u.Elem().Set(v)
+		v = u
+		fallthrough
+	case reflect.Ptr:
+		if v.Elem().Kind() != reflect.Array {
+			return nil, nil
+		}
+		info := new(encodeListInfo)
+		info.init(v.Elem(), eg)
+		return unsafe.Pointer(v.Pointer()), info
+	default:
+		return nil, nil
+	}
+	info := new(encodeListInfo)
+	info.init(v, eg)
+	return unsafe.Pointer(v.Pointer()), info
+}
+
 // typePtrEncoder returns the pointer encoder and message size based on the given v.
 // It panics if v's Kind is not Array, Struct, Bool,
 // Int8, Uint8, Int16, Uint16, Int32, Uint32, Int64, Uint64,
@@ -191,7 +281,7 @@ func (eg *EncoderGroup) typePtrEncoder(v reflect.Value) (ptrEncoder, int) {
 		return info.encode, info.num * info.eleSize
 	case reflect.Struct:
 		info := eg.getEncodeStructInfo(v)
-		return info.encode, info.size
+		return info.encoder, info.size
 	case reflect.Bool:
 		return eg.ptrInfo.bool, 1
 	case reflect.Int8:
@@ -2,6 +2,7 @@ package alignbinary
 
 import (
 	"encoding/binary"
+	"io"
 	"reflect"
 	"unsafe"
 )
@@ -14,7 +15,10 @@ type encodeListInfo struct {
 	// num is the number of all elements.
 	num int
 	// eleSize is the size of an element.
-	eleSize    int
+	eleSize int
+	// eleStride is the distance in memory between two successive elements,
+	// which differs from the eleSize when the alignment factor is not AlignDefault.
+	eleStride  uintptr
 	eleEncoder ptrEncoder
 }
 
@@ -23,24 +27,62 @@ func (li *encodeListInfo) init(v reflect.Value, eg *EncoderGroup) {
 	if li.num == 0 {
 		return
 	}
+	li.eleStride = v.Type().Elem().Size()
 	li.eleEncoder,li.eleSize = eg.typePtrEncoder(v.Index(0))
 	return
 }
 
 func (li *encodeListInfo) encode(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
+	li.encodeRange(ptr, 0, li.num, buf, order)
+}
+
+// encodeRange encodes the elements in the range [i, j) into the buf,
+// the first of them is encoded at the start of the buf.
+func (li *encodeListInfo) encodeRange(ptr unsafe.Pointer, i, j int, buf []byte, order binary.ByteOrder) {
 	var elePtr unsafe.Pointer
-	var offset uintptr
-	for i := 0; i < li.num; i++ {
-		offset = uintptr(i * li.eleSize)
-		elePtr = offsetPtr(ptr, offset)
-		li.eleEncoder(elePtr, buf[offset:], order)
+	for k := i; k < j; k++ {
+		elePtr = offsetPtr(ptr, uintptr(k)*li.eleStride)
+		li.eleEncoder(elePtr, buf[(k-i)*li.eleSize:], order)
+	}
+}
+
+// encodeParallel is like encode but encodes the elements in element-aligned chunks
+// by the given number of goroutines.
+func (li *encodeListInfo) encodeParallel(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder, workers int) {
+	parallelRange(li.num, workers, func(i, j int) {
+		li.encodeRange(ptr, i, j, buf[i*li.eleSize:], order)
+	})
+}
+
+// write encodes the elements in chunks and writes them into w,
+// ptr points to the first element and buf is the scratch buffer.
+// It returns the scratch buffer and the number of bytes written.
+func (li *encodeListInfo) write(w io.Writer, ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) ([]byte, int, error) {
+	n := chunkLen(li.num, li.eleSize)
+	buf = grow(buf, n*li.eleSize)
+	var written int
+	for i := 0; i < li.num; i += n {
+		j := i + n
+		if j > li.num {
+			j = li.num
+		}
+		data := buf[:(j-i)*li.eleSize]
+		li.encodeRange(ptr, i, j, data, order)
+		m, err := w.Write(data)
+		written += m
+		if err != nil {
+			return buf, written, err
+		}
 	}
+	return buf, written, nil
 }
 
 type encodeStructInfo struct {
 	// size is the size of the struct.
 	size   int
 	fields []*encodeFieldInfo
+	// encoder is the method value of encode bound once, as binding it allocates.
+	encoder ptrEncoder
 }
 
 type encodeFieldInfo struct {
@@ -69,6 +111,7 @@ func (si *encodeStructInfo) init(v reflect.Value, eg *EncoderGroup) {
 	}
 	si.fields = fields
 	si.size = int(st.size)
+	si.encoder = si.encode
 }
 
 func (si *encodeStructInfo) encode(ptr unsafe.Pointer, buf []byte, order binary.ByteOrder) {
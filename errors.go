@@ -0,0 +1,153 @@
+package alignbinary
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrTooManyRecords is returned by ReadAll if the input has more elements
+// than the limit set by WithMaxRecords.
+var ErrTooManyRecords = errors.New("alignbinary: too many records")
+
+// DecodeError describes a message which can't be decoded because the data
+// is too short, or reading the data failed. It's also returned by Get and Set
+// if the data is too short to contain the field.
+// Use errors.Is to check the underlying error, e.g. io.ErrUnexpectedEOF.
+type DecodeError struct {
+	// Offset is the offset of the first field which is not complete,
+	// from the start of the message, in bytes.
+	Offset int64
+	// Need is the size of the binary representation of the message, in bytes,
+	// or the end offset of the field accessed by Get or Set.
+	Need int
+	// Have is the number of bytes available, or read before the error.
+	Have int
+	// FieldPath is the path of the field at the Offset, e.g. "Hdr.Seq",
+	// or "[3].Seq" for the element 3 of a slice or an array.
+	// It's empty if the message is a basic type value,
+	// or only the trailing padding bytes are missing.
+	FieldPath string
+	// Type is the type of the message, the element type if it's a pointer.
+	// It's nil for the padding skipped by StreamDecoder.AlignTo.
+	Type reflect.Type
+	// Err is the underlying error, io.ErrUnexpectedEOF or the error of the reader.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	var s string
+	if e.Type == nil {
+		s = fmt.Sprintf("alignbinary: cannot skip padding: have %v of %v bytes", e.Have, e.Need)
+	} else {
+		s = fmt.Sprintf("alignbinary: cannot decode %v: have %v of %v bytes", e.Type, e.Have, e.Need)
+	}
+	if e.FieldPath != "" {
+		s += fmt.Sprintf(", field %v at offset %v is not complete", e.FieldPath, e.Offset)
+	} else {
+		s += fmt.Sprintf(", stopped at offset %v", e.Offset)
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// TrailingDataError describes the data longer than the message decoded exactly.
+type TrailingDataError struct {
+	// Size is the size of the binary representation of the message, in bytes.
+	Size int
+	// Have is the length of the data, in bytes.
+	Have int
+	// Type is the type of the message, the element type if it's a pointer.
+	// It's nil for an empty sequence decoded by DecodeSequence.
+	Type reflect.Type
+}
+
+func (e *TrailingDataError) Error() string {
+	if e.Type == nil {
+		return fmt.Sprintf("alignbinary: %v bytes of trailing data after an empty sequence", e.Have-e.Size)
+	}
+	return fmt.Sprintf("alignbinary: %v bytes of trailing data after %v bytes of %v", e.Have-e.Size, e.Size, e.Type)
+}
+
+func newTrailingDataError(msg interface{}, size, have int) *TrailingDataError {
+	t := reflect.TypeOf(msg)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &TrailingDataError{Size: size, Have: have, Type: t}
+}
+
+// decodeError returns a DecodeError of msg, whose binary representation has
+// the size need, but only have bytes are available.
+func (dg *DecoderGroup) decodeError(msg interface{}, need, have int, err error) *DecodeError {
+	t := reflect.TypeOf(msg)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	path, offset := locateField(t, dg.af, 0, have)
+	return &DecodeError{Offset: int64(offset), Need: need, Have: have, FieldPath: path, Type: t, Err: err}
+}
+
+// readError returns the error of reading msg after n bytes are read.
+// The io.EOF is returned as it is if no bytes are read, so the end of a stream
+// between the messages can be detected like the package encoding/binary does.
+func (dg *DecoderGroup) readError(msg interface{}, need, n int, err error) error {
+	if err == io.EOF {
+		if n == 0 {
+			return err
+		}
+		err = io.ErrUnexpectedEOF
+	}
+	return dg.decodeError(msg, need, n, err)
+}
+
+// locateField returns the path and offset of the first field of type t,
+// which starts at the base offset, not complete within the first have bytes.
+func locateField(t reflect.Type, af AlignFactor, base, have int) (string, int) {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		elemSize := typeSize(t.Elem(), af)
+		if elemSize == 0 {
+			return "", base
+		}
+		// Report the element containing the end of the data.
+		i := 0
+		if have > base {
+			i = (have - base) / int(elemSize)
+		}
+		if t.Kind() == reflect.Array && i >= t.Len() {
+			return "", base + t.Len()*int(elemSize)
+		}
+		path, offset := locateField(t.Elem(), af, base+i*int(elemSize), have)
+		return fmt.Sprintf("[%v]", i) + dotted(path), offset
+	case reflect.Struct:
+		st := structTyp{}
+		st.init(t, af)
+		end := base
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			start := base + int(st.fields[i])
+			size := typeSize(f.Type, af)
+			if start+int(size) > have {
+				path, offset := locateField(f.Type, af, start, have)
+				return f.Name + dotted(path), offset
+			}
+			end = start + int(size)
+		}
+		// Only the trailing padding bytes are missing.
+		return "", end
+	}
+	return "", base
+}
+
+// dotted returns the path to be appended to the path of a struct field.
+func dotted(path string) string {
+	if path == "" || path[0] == '[' {
+		return path
+	}
+	return "." + path
+}
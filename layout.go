@@ -0,0 +1,215 @@
+package alignbinary
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// StructLayout describes the binary representation of a struct type
+// based on an alignment factor.
+type StructLayout struct {
+	// TypeName is the name of the struct type.
+	TypeName string
+	// Factor is the alignment factor the layout is based on.
+	Factor AlignFactor
+	// Size is the size of the struct, in bytes.
+	Size uintptr
+	// Align is the alignment of the struct, in bytes.
+	Align uintptr
+	// TrailingPad is the number of padding bytes after the last field.
+	TrailingPad uintptr
+	// Fields are the layouts of all fields in declaration order.
+	Fields []FieldLayout
+}
+
+// FieldLayout describes the binary representation of a field within a struct.
+type FieldLayout struct {
+	// Name is the name of the field.
+	Name string
+	// Path is the dotted path of the field from the outermost struct, e.g. "Hdr.Flags".
+	Path string
+	// TypeName is the name of the field type.
+	TypeName string
+	// Kind is the kind of the field type.
+	Kind reflect.Kind
+	// Len is the number of elements if the Kind is Array.
+	Len int
+	// Offset is the offset from the start of the outermost struct, in bytes.
+	Offset uintptr
+	// Size is the size of the field, in bytes.
+	Size uintptr
+	// Align is the alignment of the field, in bytes.
+	Align uintptr
+	// PadBefore is the number of padding bytes between the previous field
+	// (or the start of the enclosing struct) and the field.
+	PadBefore uintptr
+	// PadAfter is the number of padding bytes between the field
+	// and the next field (or the end of the enclosing struct).
+	PadAfter uintptr
+	// Fields are the layouts of the nested fields if the field is a struct,
+	// or an array of structs, in which case they describe the first element.
+	Fields []FieldLayout
+}
+
+// Layout returns the layout of the struct type t based on the given af,
+// all nested structs are described recursively.
+//
+// It returns an error if t is not a struct type, or contains a field
+// of a type that can't be encoded. It panics if af is invalid.
+func Layout(t reflect.Type, af AlignFactor) (*StructLayout, error) {
+	checkAlignFactor(af)
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("alignbinary: layout of non-struct type %v", t)
+	}
+	if err := checkType(t, t.Name()); err != nil {
+		return nil, err
+	}
+	size := typeSize(t, af)
+	sl := &StructLayout{TypeName: t.String(), Factor: af, Size: size, Align: uintptr(typeAlign(t, af))}
+	sl.Fields = structFields(t, af, "", 0, size)
+	if n := len(sl.Fields); n > 0 {
+		sl.TrailingPad = sl.Fields[n-1].PadAfter
+	} else {
+		sl.TrailingPad = size
+	}
+	return sl, nil
+}
+
+// structFields returns the layouts of the fields of the struct type t,
+// which starts at the base offset and has the given size.
+func structFields(t reflect.Type, af AlignFactor, path string, base, size uintptr) []FieldLayout {
+	st := structTyp{}
+	st.init(t, af)
+	n := t.NumField()
+	fields := make([]FieldLayout, n)
+	var end uintptr
+	for i := 0; i < n; i++ {
+		f := t.Field(i)
+		fl := &fields[i]
+		fl.Name = f.Name
+		fl.Path = f.Name
+		if path != "" {
+			fl.Path = path + "." + f.Name
+		}
+		fl.TypeName = f.Type.String()
+		fl.Kind = f.Type.Kind()
+		fl.Offset = base + st.fields[i]
+		fl.Size, fl.Align = typeSize(f.Type, af), uintptr(typeAlign(f.Type, af))
+		fl.PadBefore = st.fields[i] - end
+		if i > 0 {
+			fields[i-1].PadAfter = fl.PadBefore
+		}
+		end = st.fields[i] + fl.Size
+
+		elem := f.Type
+		if fl.Kind == reflect.Array {
+			fl.Len = elem.Len()
+			elem = elem.Elem()
+			for elem.Kind() == reflect.Array {
+				elem = elem.Elem()
+			}
+		}
+		if elem.Kind() == reflect.Struct {
+			elemSize := typeSize(elem, af)
+			fl.Fields = structFields(elem, af, fl.Path, fl.Offset, elemSize)
+		}
+	}
+	if n > 0 {
+		fields[n-1].PadAfter = size - end
+	}
+	return fields
+}
+
+// checkType returns an error if t, named by the path, or any type it contains
+// can't be encoded.
+func checkType(t reflect.Type, path string) error {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Uint8, reflect.Int16, reflect.Uint16,
+		reflect.Int32, reflect.Uint32, reflect.Int64, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return nil
+	case reflect.Array:
+		return checkType(t.Elem(), path+"[]")
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if err := checkType(f.Type, path+"."+f.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("alignbinary: %v has invalid type %v", path, t)
+}
+
+// checkSameLayout returns an error if the binary representation of t, named by the path,
+// based on the given af is not identical to its memory layout, so a value of t
+// can't be used in place. The bools are not, as they're decoded from any non-zero byte,
+// nor are the skipped struct fields, as they're encoded as zeros.
+func checkSameLayout(t reflect.Type, af AlignFactor, path string) error {
+	size := typeSize(t, af)
+	if size != t.Size() {
+		return fmt.Errorf("alignbinary: %v has size %v in memory but %v in binary", path, t.Size(), size)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return fmt.Errorf("alignbinary: %v is a bool, which is decoded from any non-zero byte", path)
+	case reflect.Array:
+		return checkSameLayout(t.Elem(), af, path+"[]")
+	case reflect.Struct:
+		st := structTyp{}
+		st.init(t, af)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			fPath := path + "." + f.Name
+			if f.Name == "_" || !f.IsExported() {
+				return fmt.Errorf("alignbinary: %v is skipped in binary", fPath)
+			}
+			if st.fields[i] != f.Offset {
+				return fmt.Errorf("alignbinary: %v is at offset %v in memory but %v in binary", fPath, f.Offset, st.fields[i])
+			}
+			if err := checkSameLayout(f.Type, af, fPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// String returns a table of the layout in the style of pahole.
+func (sl *StructLayout) String() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "type %v struct {\n", sl.TypeName)
+	tw := tabwriter.NewWriter(buf, 0, 4, 1, ' ', 0)
+	fmt.Fprintf(tw, "\t\t// offset  size align\n")
+	writeFields(tw, sl.Fields, 1)
+	if sl.TrailingPad > 0 {
+		fmt.Fprintf(tw, "\t\t// XXX %v trailing padding\n", byteCount(sl.TrailingPad))
+	}
+	tw.Flush()
+	fmt.Fprintf(buf, "}\n// size: %v, alignment: %v, trailing padding: %v\n", sl.Size, sl.Align, sl.TrailingPad)
+	return buf.String()
+}
+
+// byteCount returns the number of bytes n with the unit, e.g. "1 byte" or "2 bytes".
+func byteCount(n uintptr) string {
+	if n == 1 {
+		return "1 byte"
+	}
+	return fmt.Sprintf("%v bytes", n)
+}
+
+// writeFields writes a row for each of fields indented by the depth.
+func writeFields(tw *tabwriter.Writer, fields []FieldLayout, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for _, f := range fields {
+		if f.PadBefore > 0 {
+			fmt.Fprintf(tw, "\t\t// XXX %v hole\n", byteCount(f.PadBefore))
+		}
+		fmt.Fprintf(tw, "%v%v\t%v\t// %6d %5d %5d\n", indent, f.Name, f.TypeName, f.Offset, f.Size, f.Align)
+		writeFields(tw, f.Fields, depth+1)
+	}
+}
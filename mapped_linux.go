@@ -0,0 +1,215 @@
+//go:build linux
+
+package alignbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"syscall"
+	"unsafe"
+)
+
+// MappedArray is a flat array of fixed-size records of type T stored in a file
+// mapped into memory, the stride of the records is the size of T encoded by a Codec.
+//
+// If the binary representation of T is identical to its memory layout, i.e. the Codec
+// uses the host byte order and an alignment factor which doesn't move any field,
+// At returns a pointer into the mapping and the records are accessed without copying.
+// Otherwise, the records are decoded and encoded on access.
+//
+// A MappedArray is safe for concurrent reads and writes of the records,
+// which are not synchronized with each other, but not with Grow or Close.
+type MappedArray[T any] struct {
+	f      *os.File
+	c      *Codec
+	data   []byte
+	prot   int
+	stride int
+	// direct reports whether the records can be accessed in place.
+	direct bool
+}
+
+// NewMappedArray maps the file f, whose size must be a whole number of records,
+// into memory. The records are encoded by c, or a Codec created without any option
+// if c is nil. The mapping is writable if f is opened for writing.
+//
+// The file is still owned by the caller, which must call Close before closing it.
+func NewMappedArray[T any](f *os.File, c *Codec) (*MappedArray[T], error) {
+	if c == nil {
+		c = NewCodec()
+	}
+	ma := &MappedArray[T]{f: f, c: c, prot: syscall.PROT_READ}
+	ma.stride = recordSize(c.dg, new(T))
+	ma.direct = isHostOrder(c.order) && checkSameLayout(reflect.TypeOf((*T)(nil)).Elem(), c.af, "") == nil
+	flags, err := fcntl(f, syscall.F_GETFL)
+	if err != nil {
+		return nil, err
+	}
+	if flags&syscall.O_ACCMODE == syscall.O_RDWR {
+		ma.prot |= syscall.PROT_WRITE
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size()%int64(ma.stride) != 0 {
+		return nil, fmt.Errorf("alignbinary: size %v of mapped file is not a whole number of records of %v bytes",
+			fi.Size(), ma.stride)
+	}
+	if err = ma.mmap(fi.Size()); err != nil {
+		return nil, err
+	}
+	return ma, nil
+}
+
+// Len returns the number of records.
+func (ma *MappedArray[T]) Len() int {
+	return len(ma.data) / ma.stride
+}
+
+// Stride returns the size of a record, in bytes.
+func (ma *MappedArray[T]) Stride() int {
+	return ma.stride
+}
+
+// Direct reports whether the records are accessed in place,
+// see the MappedArray for details.
+func (ma *MappedArray[T]) Direct() bool {
+	return ma.direct
+}
+
+// At returns the record i. If the MappedArray is Direct, the returned pointer
+// points into the mapping, which is valid until the next Grow or Close, and the
+// changes made through it are written to the file. It must not be changed if the
+// mapping is not writable.
+// Otherwise, it returns a decoded copy of the record, use Set to change it.
+//
+// It panics if i is out of range.
+func (ma *MappedArray[T]) At(i int) *T {
+	rec := ma.record(i)
+	if ma.direct {
+		return (*T)(unsafe.Pointer(&rec[0]))
+	}
+	v := new(T)
+	decoder, _ := ma.c.dg.bindMsg(v, ma.c.order)
+	decoder(rec)
+	return v
+}
+
+// Get decodes the record i into rec. It panics if i is out of range.
+func (ma *MappedArray[T]) Get(i int, rec *T) {
+	if ma.direct {
+		*rec = *ma.At(i)
+		return
+	}
+	decoder, _ := ma.c.dg.bindMsg(rec, ma.c.order)
+	decoder(ma.record(i))
+}
+
+// Set overwrites the record i with rec. It panics if i is out of range,
+// and returns errReadOnly if the mapping is not writable.
+func (ma *MappedArray[T]) Set(i int, rec *T) error {
+	if ma.prot&syscall.PROT_WRITE == 0 {
+		return errReadOnly
+	}
+	if ma.direct {
+		*ma.At(i) = *rec
+		return nil
+	}
+	encoder, _ := ma.c.eg.bindMsg(rec, ma.c.order)
+	encoder(ma.record(i))
+	return nil
+}
+
+// record returns the bytes of the record i.
+func (ma *MappedArray[T]) record(i int) []byte {
+	if i < 0 || i >= ma.Len() {
+		panic(fmt.Sprintf("alignbinary: record index %v out of range [0, %v)", i, ma.Len()))
+	}
+	return ma.data[i*ma.stride : (i+1)*ma.stride : (i+1)*ma.stride]
+}
+
+// Grow extends the file by n zero records and remaps it. The pointers returned by At
+// are invalid after Grow, which must not be called concurrently with other methods.
+func (ma *MappedArray[T]) Grow(n int) error {
+	if ma.prot&syscall.PROT_WRITE == 0 {
+		return errReadOnly
+	}
+	if n < 0 {
+		return fmt.Errorf("alignbinary: cannot grow mapped array by %v records", n)
+	}
+	size := int64(len(ma.data)) + int64(n)*int64(ma.stride)
+	if err := ma.f.Truncate(size); err != nil {
+		return err
+	}
+	if err := ma.munmap(); err != nil {
+		return err
+	}
+	return ma.mmap(size)
+}
+
+// Sync flushes the changes of the records to the file, and waits until it's done.
+func (ma *MappedArray[T]) Sync() error {
+	if len(ma.data) == 0 {
+		return nil
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_MSYNC, uintptr(unsafe.Pointer(&ma.data[0])),
+		uintptr(len(ma.data)), syscall.MS_SYNC)
+	if errno != 0 {
+		return os.NewSyscallError("msync", errno)
+	}
+	return nil
+}
+
+// Close unmaps the file without closing it. The pointers returned by At
+// are invalid after Close.
+func (ma *MappedArray[T]) Close() error {
+	return ma.munmap()
+}
+
+// mmap maps the first size bytes of the file.
+func (ma *MappedArray[T]) mmap(size int64) error {
+	if size == 0 {
+		// An empty mapping is invalid.
+		return nil
+	}
+	if int64(int(size)) != size {
+		return fmt.Errorf("alignbinary: size %v of mapped file is too large", size)
+	}
+	data, err := syscall.Mmap(int(ma.f.Fd()), 0, int(size), ma.prot, syscall.MAP_SHARED)
+	if err != nil {
+		return os.NewSyscallError("mmap", err)
+	}
+	ma.data = data
+	return nil
+}
+
+// munmap unmaps the file if it's mapped.
+func (ma *MappedArray[T]) munmap() error {
+	if ma.data == nil {
+		return nil
+	}
+	data := ma.data
+	ma.data = nil
+	if err := syscall.Munmap(data); err != nil {
+		return os.NewSyscallError("munmap", err)
+	}
+	return nil
+}
+
+// fcntl returns the result of the fcntl command cmd on the file f.
+func fcntl(f *os.File, cmd int) (int, error) {
+	r, _, errno := syscall.Syscall(syscall.SYS_FCNTL, f.Fd(), uintptr(cmd), 0)
+	if errno != 0 {
+		return 0, os.NewSyscallError("fcntl", errno)
+	}
+	return int(r), nil
+}
+
+// isHostOrder reports whether the order is the byte order of the host.
+func isHostOrder(order binary.ByteOrder) bool {
+	b := []byte{1, 2}
+	return order.Uint16(b) == binary.NativeEndian.Uint16(b)
+}
@@ -0,0 +1,79 @@
+//go:build linux
+
+package alignbinary
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMappedArray(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "records")
+	for _, c := range []*Codec{
+		NewCodec(WithProfile(ProfileNative)),
+		NewCodec(WithAlign(Align1Byte), WithOrder(binary.NativeEndian)),
+	} {
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ma, err := NewMappedArray[sequenceStruct](f, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkResult(t, "TestMappedArray", order, nil, ma.Direct(), c.Align() == AlignDefault)
+		checkResult(t, "TestMappedArray", order, nil, ma.Len(), 0)
+		err = ma.Grow(1000)
+		checkResult(t, "TestMappedArray", order, err, ma.Len(), 1000)
+		for i := 0; i < ma.Len(); i++ {
+			err = ma.Set(i, &sequenceStruct{uint8(i), [2]uint32{uint32(i), 2}, 3})
+			checkResult(t, "TestMappedArray", order, err, nil, nil)
+		}
+		if ma.Direct() {
+			ma.At(7).Trailer = 9
+		}
+		err = ma.Sync()
+		checkResult(t, "TestMappedArray", order, err, nil, nil)
+		checkResult(t, "TestMappedArray", order, ma.Close(), nil, nil)
+		f.Close()
+
+		// Reopen the file read-only and compare with the encoded records.
+		f, err = os.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ma, err = NewMappedArray[sequenceStruct](f, c)
+		checkResult(t, "TestMappedArray", order, err, ma.Len(), 1000)
+		data, _ := os.ReadFile(name)
+		recs := make([]sequenceStruct, 1000)
+		err = c.Unmarshal(data, recs)
+		checkResult(t, "TestMappedArray", order, err, nil, nil)
+		var rec sequenceStruct
+		for i := range recs {
+			ma.Get(i, &rec)
+			if *ma.At(i) != recs[i] || rec != recs[i] {
+				t.Errorf("TestMappedArray: have %v at %v, want %v", *ma.At(i), i, recs[i])
+			}
+		}
+		want := sequenceStruct{7, [2]uint32{7, 2}, 3}
+		if ma.Direct() {
+			want.Trailer = 9
+		}
+		checkResult(t, "TestMappedArray", order, nil, *ma.At(7), want)
+		if err = ma.Set(0, &rec); err != errReadOnly {
+			t.Errorf("TestMappedArray: have %v, want %v", err, errReadOnly)
+		}
+		ma.Close()
+		f.Close()
+	}
+
+	if checkSameLayout(reflect.TypeOf(struct{ A, B bool }{}), AlignDefault, "") == nil {
+		t.Errorf("TestMappedArray: have the same layout of bools")
+	}
+	if err := checkSameLayout(reflect.TypeOf(layoutStruct{}), Align8Byte, ""); err != nil {
+		t.Errorf("TestMappedArray: have %v for layoutStruct aligned by 8 bytes", err)
+	}
+}
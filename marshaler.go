@@ -0,0 +1,50 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+)
+
+// AlignedMarshaler is implemented by the types with the encoding methods
+// generated by the command 'alignbinary gen-marshal'.
+// The EncoderGroup prefers the methods to the reflection if the alignment factors match.
+type AlignedMarshaler interface {
+	// AlignedLayout returns the alignment factor the methods are generated for,
+	// and the size of the binary representation, in bytes.
+	AlignedLayout() (AlignFactor, int)
+	// AppendAligned appends the binary representation to buf and returns the extended buffer.
+	AppendAligned(buf []byte, order binary.ByteOrder) []byte
+}
+
+// AlignedUnmarshaler is implemented by the types with the decoding methods
+// generated by the command 'alignbinary gen-marshal'.
+// The DecoderGroup prefers the methods to the reflection if the alignment factors match.
+type AlignedUnmarshaler interface {
+	// AlignedLayout returns the alignment factor the methods are generated for,
+	// and the size of the binary representation, in bytes.
+	AlignedLayout() (AlignFactor, int)
+	// UnmarshalAligned decodes the binary representation from the start of data.
+	// It returns io.ErrUnexpectedEOF if data is too short.
+	UnmarshalAligned(data []byte, order binary.ByteOrder) error
+}
+
+// assertMarshaler returns msg as an AlignedMarshaler and its size
+// if it's generated for the af, otherwise nil and -1.
+func assertMarshaler(msg interface{}, af AlignFactor) (AlignedMarshaler, int) {
+	if m, ok := msg.(AlignedMarshaler); ok {
+		if maf, size := m.AlignedLayout(); maf == af {
+			return m, size
+		}
+	}
+	return nil, -1
+}
+
+// assertUnmarshaler returns msg as an AlignedUnmarshaler and its size
+// if it's generated for the af, otherwise nil and -1.
+func assertUnmarshaler(msg interface{}, af AlignFactor) (AlignedUnmarshaler, int) {
+	if m, ok := msg.(AlignedUnmarshaler); ok {
+		if maf, size := m.AlignedLayout(); maf == af {
+			return m, size
+		}
+	}
+	return nil, -1
+}
@@ -0,0 +1,88 @@
+package alignbinary_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/happyxcj/alignbinary"
+	"github.com/happyxcj/alignbinary/testdata/aligned"
+)
+
+// countedSequence counts the calls of the methods of aligned.Sequence,
+// which are generated by 'alignbinary gen-marshal -align 1'.
+type countedSequence struct {
+	aligned.Sequence
+}
+
+var sequenceCalls int
+
+func (m countedSequence) AppendAligned(buf []byte, order binary.ByteOrder) []byte {
+	sequenceCalls++
+	return m.Sequence.AppendAligned(buf, order)
+}
+
+func (m *countedSequence) UnmarshalAligned(b []byte, order binary.ByteOrder) error {
+	sequenceCalls++
+	return m.Sequence.UnmarshalAligned(b, order)
+}
+
+// plainSequence is aligned.Sequence without the methods, which is encoded by the reflection.
+type plainSequence aligned.Sequence
+
+func checkAligned(t *testing.T, err error, have, want interface{}) {
+	t.Helper()
+	if err != nil {
+		t.Errorf("%v: %v", t.Name(), err)
+		return
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("%v:\n\thave %+v\n\twant %+v", t.Name(), have, want)
+	}
+}
+
+func TestAlignedMarshaler(t *testing.T) {
+	order := binary.LittleEndian
+	msg := countedSequence{aligned.Sequence{Hdr: 1, Body: [2]uint32{2, 3}, Trailer: 4}}
+	for _, af := range []alignbinary.AlignFactor{alignbinary.Align1Byte, alignbinary.AlignDefault} {
+		eg, dg := alignbinary.NewEncoderGroup(af), alignbinary.NewDecoderGroup(af)
+		want, _ := eg.Encode(order, plainSequence(msg.Sequence))
+		sequenceCalls = 0
+		data, err := eg.Encode(order, msg)
+		checkAligned(t, err, data, want)
+
+		buf := &bytes.Buffer{}
+		err = eg.Write(buf, order, &msg)
+		checkAligned(t, err, buf.Bytes(), want)
+
+		var val, readVal countedSequence
+		err = dg.Decode(data, order, &val)
+		checkAligned(t, err, val, msg)
+		err = dg.Read(buf, order, &readVal)
+		checkAligned(t, err, readVal, msg)
+
+		// The methods are only used for the alignment factor they are generated for.
+		wantCalls := 0
+		if af == alignbinary.Align1Byte {
+			wantCalls = 4
+		}
+		if sequenceCalls != wantCalls {
+			t.Errorf("TestAlignedMarshaler: have %v calls for %v, want %v", sequenceCalls, af, wantCalls)
+		}
+	}
+	if err := alignbinary.NewDecoderGroup(alignbinary.Align1Byte).Decode(make([]byte, 10), order, &countedSequence{}); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("TestAlignedMarshaler: have error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// The memory is not zeroed, as the one allocated by C.
+	mem := bytes.Repeat([]byte{0xff}, 16)
+	sequenceCalls = 0
+	c := alignbinary.NewCodec(alignbinary.WithAlign(alignbinary.Align1Byte), alignbinary.WithOrder(order))
+	n, err := c.EncodeToPointer(unsafe.Pointer(&mem[0]), len(mem), msg)
+	checkAligned(t, err, mem[:n], []byte{1, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0})
+	checkAligned(t, nil, sequenceCalls, 1)
+}
@@ -0,0 +1,79 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unsafe"
+)
+
+// EncodeToPointer encodes msg into the n bytes of memory at p, e.g. allocated by C.malloc,
+// and returns the number of bytes written. The structs are laid out based on the
+// alignment factor of eg, e.g. the layout of the matching C struct, without encoding
+// into a Go slice first. The padding bytes are zeroed, as the memory may be not.
+//
+// It returns an error wrapping io.ErrShortBuffer if msg doesn't fit in n bytes,
+// in which case the memory is not touched.
+// See Encode for the supported msg.
+func (eg *EncoderGroup) EncodeToPointer(p unsafe.Pointer, n int, order binary.ByteOrder, msg interface{}) (int, error) {
+	buf, err := pointerBytes(p, n)
+	if err != nil {
+		return 0, err
+	}
+	if encoder, size := eg.assertMsg(msg); size != -1 {
+		// Fast path for a basic type value, or a slice of basic type values.
+		if size > n {
+			return 0, shortBufferError(msg, size, n)
+		}
+		encoder(msg, buf, order)
+		return size, nil
+	}
+	if m, size := assertMarshaler(msg, eg.af); size != -1 {
+		// Fast path for a type with the generated methods, which append in place
+		// as the capacity is enough.
+		if size > n {
+			return 0, shortBufferError(msg, size, n)
+		}
+		if out := m.AppendAligned(buf[:0:size], order); unsafe.SliceData(out) != unsafe.SliceData(buf) {
+			copy(buf, out)
+		}
+		return size, nil
+	}
+	// Encode by reflecting the msg.
+	ptr, encoder, size := eg.reflectMsg(msg)
+	if size > n {
+		return 0, shortBufferError(msg, size, n)
+	}
+	clear(buf[:size])
+	encoder(ptr, buf, order)
+	return size, nil
+}
+
+// DecodeFromPointer decodes msg from the n bytes of memory at p, e.g. allocated by C,
+// without copying it into a Go slice first. See Decode for the supported msg and
+// the errors, the memory must not be changed during decoding.
+func (dg *DecoderGroup) DecodeFromPointer(p unsafe.Pointer, n int, order binary.ByteOrder, msg interface{}) error {
+	data, err := pointerBytes(p, n)
+	if err != nil {
+		return err
+	}
+	return dg.decode(data, order, msg, dg.exact)
+}
+
+// pointerBytes returns the n bytes of memory at p as a slice.
+func pointerBytes(p unsafe.Pointer, n int) ([]byte, error) {
+	switch {
+	case n < 0:
+		return nil, fmt.Errorf("alignbinary: invalid memory size %v", n)
+	case p == nil && n > 0:
+		return nil, fmt.Errorf("alignbinary: nil pointer to %v bytes of memory", n)
+	case n == 0:
+		return nil, nil
+	}
+	return unsafe.Slice((*byte)(p), n), nil
+}
+
+// shortBufferError returns the error of encoding msg of the given size into have bytes.
+func shortBufferError(msg interface{}, size, have int) error {
+	return fmt.Errorf("alignbinary: cannot encode %T of %v bytes into %v bytes: %w", msg, size, have, io.ErrShortBuffer)
+}
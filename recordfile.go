@@ -0,0 +1,191 @@
+package alignbinary
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// errReadOnly is returned by writing a RecordFile not backed by an io.WriterAt.
+var errReadOnly = errors.New("alignbinary: record file is read-only")
+
+// RecordFile is a flat array of fixed-size records of type T stored in an io.ReaderAt,
+// and an io.WriterAt if it's writable, optionally after a fixed-size header.
+// The stride of the records is the size of T encoded by a Codec.
+//
+// A RecordFile has no seeking state, so it's safe for concurrent use
+// by multiple goroutines as long as the storage is. The writes of the same record
+// are not synchronized with the reads of it.
+type RecordFile[T any] struct {
+	r      io.ReaderAt
+	w      io.WriterAt
+	c      *Codec
+	header int64
+	stride int
+	// n is the number of records.
+	n atomic.Int64
+	// appendMu serializes the calls of Append.
+	appendMu sync.Mutex
+}
+
+// RecordFileOption configures a RecordFile.
+type RecordFileOption func(cfg *recordFileConfig)
+
+type recordFileConfig struct {
+	header int64
+}
+
+// WithHeader reserves the first n bytes of the storage for a header,
+// the records start after it.
+func WithHeader(n int64) RecordFileOption {
+	return func(cfg *recordFileConfig) {
+		cfg.header = n
+	}
+}
+
+// NewRecordFile returns a RecordFile of the storage r, whose size in bytes is size,
+// e.g. the size of a file. The records are encoded by c, or a Codec created
+// without any option if c is nil. The RecordFile is writable if r implements io.WriterAt.
+//
+// It returns an error if the size is not the header plus a whole number of records.
+func NewRecordFile[T any](r io.ReaderAt, size int64, c *Codec, opts ...RecordFileOption) (*RecordFile[T], error) {
+	cfg := recordFileConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if c == nil {
+		c = NewCodec()
+	}
+	rf := &RecordFile[T]{r: r, c: c, header: cfg.header}
+	rf.w, _ = r.(io.WriterAt)
+	rf.stride = recordSize(c.dg, new(T))
+	if size < cfg.header || (size-cfg.header)%int64(rf.stride) != 0 {
+		return nil, fmt.Errorf("alignbinary: size %v of record file is not the header of %v bytes plus records of %v bytes",
+			size, cfg.header, rf.stride)
+	}
+	rf.n.Store((size - cfg.header) / int64(rf.stride))
+	return rf, nil
+}
+
+// Len returns the number of records.
+func (rf *RecordFile[T]) Len() int {
+	return int(rf.n.Load())
+}
+
+// Stride returns the size of a record, in bytes.
+func (rf *RecordFile[T]) Stride() int {
+	return rf.stride
+}
+
+// Size returns the size of the storage used by the header and the records, in bytes.
+func (rf *RecordFile[T]) Size() int64 {
+	return rf.offset(rf.Len())
+}
+
+// Header returns a reader of the header.
+func (rf *RecordFile[T]) Header() *io.SectionReader {
+	return io.NewSectionReader(rf.r, 0, rf.header)
+}
+
+// offset returns the offset of the record i in the storage.
+func (rf *RecordFile[T]) offset(i int) int64 {
+	return rf.header + int64(i)*int64(rf.stride)
+}
+
+// checkRange returns an error if [i, j) is not a valid range of records.
+func (rf *RecordFile[T]) checkRange(i, j int) error {
+	if n := rf.Len(); i < 0 || j < i || j > n {
+		return fmt.Errorf("alignbinary: record range [%v, %v) out of range [0, %v)", i, j, n)
+	}
+	return nil
+}
+
+// ReadAt reads the record i into rec.
+func (rf *RecordFile[T]) ReadAt(i int, rec *T) error {
+	if err := rf.checkRange(i, i+1); err != nil {
+		return err
+	}
+	buf := make([]byte, rf.stride)
+	if err := rf.readAt(i, buf); err != nil {
+		return err
+	}
+	return rf.c.dg.decode(buf, rf.c.order, rec, false)
+}
+
+// ReadRange reads the records [i, j) into recs, which must have at least j-i elements.
+// The records are read in chunks, so the memory use doesn't grow with j-i.
+func (rf *RecordFile[T]) ReadRange(i, j int, recs []T) error {
+	if err := rf.checkRange(i, j); err != nil {
+		return err
+	}
+	if len(recs) < j-i {
+		return fmt.Errorf("alignbinary: %v records don't fit in %v elements", j-i, len(recs))
+	}
+	n := chunkLen(j-i, rf.stride)
+	buf := make([]byte, n*rf.stride)
+	for k := i; k < j; k += n {
+		if k+n > j {
+			n = j - k
+		}
+		data := buf[:n*rf.stride]
+		if err := rf.readAt(k, data); err != nil {
+			return err
+		}
+		if err := rf.c.dg.decode(data, rf.c.order, recs[k-i:k-i+n], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readAt reads the records from i into buf.
+func (rf *RecordFile[T]) readAt(i int, buf []byte) error {
+	n, err := rf.r.ReadAt(buf, rf.offset(i))
+	if n == len(buf) {
+		// The io.EOF at the end of the storage is not an error.
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return recordError[T](rf.c.dg, i+n/rf.stride, rf.stride, n%rf.stride, err)
+}
+
+// WriteAt overwrites the record i with rec.
+func (rf *RecordFile[T]) WriteAt(i int, rec *T) error {
+	if rf.w == nil {
+		return errReadOnly
+	}
+	if err := rf.checkRange(i, i+1); err != nil {
+		return err
+	}
+	buf := make([]byte, rf.stride)
+	encoder, _ := rf.c.eg.bindMsg(rec, rf.c.order)
+	encoder(buf)
+	_, err := rf.w.WriteAt(buf, rf.offset(i))
+	return err
+}
+
+// Append writes the recs after the last record, and returns the index of the first one.
+// The new records are visible to Len and the reads after they are written.
+func (rf *RecordFile[T]) Append(recs ...T) (int, error) {
+	if rf.w == nil {
+		return 0, errReadOnly
+	}
+	rf.appendMu.Lock()
+	defer rf.appendMu.Unlock()
+	i := rf.Len()
+	if len(recs) == 0 {
+		return i, nil
+	}
+	buf := make([]byte, len(recs)*rf.stride)
+	encoder, _ := rf.c.eg.bindMsg(recs, rf.c.order)
+	encoder(buf)
+	if _, err := rf.w.WriteAt(buf, rf.offset(i)); err != nil {
+		return i, err
+	}
+	rf.n.Add(int64(len(recs)))
+	return i, nil
+}
@@ -0,0 +1,101 @@
+package alignbinary
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// Records returns an iterator over the back-to-back records of type T read from r
+// until io.EOF, each record is decoded into a new T:
+//
+//	for rec, err := range alignbinary.Records[Tick](f, binary.LittleEndian, nil) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// T must be a fixed-size type. The records are decoded by dg,
+// or the default decoder group if dg is nil.
+//
+// If the final record is partial, or reading fails, the iterator yields
+// a *DecodeError whose FieldPath starts with the index of the record, and stops.
+func Records[T any](r io.Reader, order binary.ByteOrder, dg *DecoderGroup) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		if dg == nil {
+			dg = DefaultDecoderGroup()
+		}
+		size := recordSize(dg, new(T))
+		buf := make([]byte, size)
+		for i := 0; ; i++ {
+			n, err := io.ReadFull(r, buf)
+			if err != nil {
+				if err != io.EOF {
+					yield(nil, recordError[T](dg, i, size, n, err))
+				}
+				return
+			}
+			rec := new(T)
+			if err = dg.decode(buf, order, rec, false); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// BufferedRecords is like Records but reads ahead from r with a buffer sized to
+// a multiple of the record size, and decodes each record into the same T
+// directly from the buffer. The yielded *T is only valid until the next iteration.
+func BufferedRecords[T any](r io.Reader, order binary.ByteOrder, dg *DecoderGroup) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		if dg == nil {
+			dg = DefaultDecoderGroup()
+		}
+		rec := new(T)
+		size := recordSize(dg, rec)
+		br := bufio.NewReaderSize(r, size*chunkLen(chunkSize, size))
+		for i := 0; ; i++ {
+			data, err := br.Peek(size)
+			if err != nil {
+				if err == io.EOF && len(data) > 0 {
+					err = io.ErrUnexpectedEOF
+				}
+				if err != io.EOF {
+					yield(nil, recordError[T](dg, i, size, len(data), err))
+				}
+				return
+			}
+			if err = dg.decode(data, order, rec, false); err != nil {
+				yield(nil, err)
+				return
+			}
+			br.Discard(size)
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+// recordSize returns the size of the record rec. It panics if the size is 0,
+// which can't be iterated.
+func recordSize[T any](dg *DecoderGroup, rec *T) int {
+	size := dg.msgSize(rec)
+	if size == 0 {
+		panic(fmt.Sprintf("alignbinary: call Records on zero-size type %T", *rec))
+	}
+	return size
+}
+
+// recordError returns a *DecodeError of the record i of type T, of which
+// only have bytes are read, as if the records were the elements of a []T.
+func recordError[T any](dg *DecoderGroup, i, size, have int, err error) *DecodeError {
+	return dg.decodeError([]T(nil), (i+1)*size, i*size+have, err)
+}
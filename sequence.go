@@ -0,0 +1,119 @@
+package alignbinary
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// EncodeSequence encodes the msgs contiguously and returns their binary representation,
+// as if they were the fields of an anonymous struct in the given order.
+// So the padding bytes are inserted between the msgs and after the last one
+// based on the alignment factor of eg.
+//
+// Each of msgs must be a fixed-size value, a pointer to a fixed-size value,
+// or a slice of fixed-size values. A slice is laid out like an array.
+func (eg *EncoderGroup) EncodeSequence(order binary.ByteOrder, msgs ...interface{}) ([]byte, error) {
+	encoders := make([]func(buf []byte), len(msgs))
+	sizes := make([]int, len(msgs))
+	aligns := make([]int, len(msgs))
+	for i, msg := range msgs {
+		encoders[i], sizes[i] = eg.bindMsg(msg, order)
+		aligns[i] = msgAlign(msg, eg.af)
+	}
+	offsets, size := sequenceLayout(sizes, aligns)
+	buf := make([]byte, size)
+	for i, encoder := range encoders {
+		encoder(buf[offsets[i]:])
+	}
+	return buf, nil
+}
+
+// bindMsg returns a function to encode msg into a buf and the size of msg.
+func (eg *EncoderGroup) bindMsg(msg interface{}, order binary.ByteOrder) (func(buf []byte), int) {
+	if encoder, size := eg.assertMsg(msg); size != -1 {
+		return func(buf []byte) { encoder(msg, buf, order) }, size
+	}
+	ptr, encoder, size := eg.reflectMsg(msg)
+	return func(buf []byte) { encoder(ptr, buf, order) }, size
+}
+
+// DecodeSequence decodes the ptrs from the data encoded by EncodeSequence,
+// as if they were the fields of an anonymous struct in the given order.
+// It returns a *DecodeError wrapping io.ErrUnexpectedEOF if data is shorter than
+// the whole sequence, whose Type is the one of the first msg not complete,
+// and whose Offset is from the start of the sequence.
+// If dg is created WithExactLength, it returns a *TrailingDataError of the last msg
+// if data is longer than the whole sequence, whose Type is nil if there are no ptrs.
+//
+// Each of ptrs must be a pointer to a fixed-size value or a slice of fixed-size values.
+func (dg *DecoderGroup) DecodeSequence(data []byte, order binary.ByteOrder, ptrs ...interface{}) error {
+	decoders := make([]func(buf []byte), len(ptrs))
+	sizes := make([]int, len(ptrs))
+	aligns := make([]int, len(ptrs))
+	for i, msg := range ptrs {
+		decoders[i], sizes[i] = dg.bindMsg(msg, order)
+		aligns[i] = msgAlign(msg, dg.af)
+	}
+	offsets, size := sequenceLayout(sizes, aligns)
+	if len(data) < size {
+		// Report the first msg not complete, or the last one if only the padding is missing.
+		i := 0
+		for i < len(ptrs)-1 && offsets[i]+sizes[i] <= len(data) {
+			i++
+		}
+		err := dg.decodeError(ptrs[i], size, len(data), io.ErrUnexpectedEOF)
+		path, offset := locateField(err.Type, dg.af, offsets[i], len(data))
+		err.FieldPath, err.Offset = path, int64(offset)
+		return err
+	}
+	if dg.exact && len(data) > size {
+		if len(ptrs) == 0 {
+			return &TrailingDataError{Size: size, Have: len(data)}
+		}
+		return newTrailingDataError(ptrs[len(ptrs)-1], size, len(data))
+	}
+	for i, decoder := range decoders {
+		decoder(data[offsets[i]:])
+	}
+	return nil
+}
+
+// bindMsg returns a function to decode msg from a buf and the size of msg.
+func (dg *DecoderGroup) bindMsg(msg interface{}, order binary.ByteOrder) (func(buf []byte), int) {
+	if decoder, size := dg.assertMsg(msg); size != -1 {
+		return func(buf []byte) { decoder(msg, buf, order) }, size
+	}
+	ptr, decoder, size := dg.reflectMsg(msg)
+	return func(buf []byte) { decoder(ptr, buf, order) }, size
+}
+
+// sequenceLayout calculates the offsets of a sequence of values with the given
+// sizes and alignments, and the total size of the sequence, in bytes.
+//
+// The calculation follows the calcStructSizeAlign, the values are laid out
+// like the fields of a struct.
+func sequenceLayout(sizes, aligns []int) ([]int, int) {
+	offsets := make([]int, len(sizes))
+	var size int
+	maxAlign := 1
+	lastZero := 0
+	for i := range sizes {
+		if aligns[i] > maxAlign {
+			maxAlign = aligns[i]
+		}
+		offset := size
+		if aligns[i] > 0 {
+			offset = int(align(uintptr(size), uintptr(aligns[i])))
+		}
+		size = offset + sizes[i]
+		if sizes[i] == 0 {
+			lastZero = size
+		}
+		offsets[i] = offset
+	}
+	if size > 0 && lastZero == size {
+		// Keep the same padding as a struct ending in a zero-sized field.
+		size++
+	}
+	return offsets, int(align(uintptr(size), uintptr(maxAlign)))
+}
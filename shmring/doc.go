@@ -0,0 +1,32 @@
+// Package shmring implements a single-producer, multi-consumer ring buffer of
+// fixed-size records in a shared memory file, e.g. in /dev/shm, for exchanging
+// records with other processes, including the ones written in C.
+//
+// The file starts with a header of 64 bytes, all fields in the byte order of the host:
+//
+//	offset  size  field
+//	0       4     magic, 0x41425247
+//	4       4     version, 1
+//	8       4     slot size, the size of a record encoded by alignbinary
+//	12      4     capacity, the number of slots, a power of two
+//	16      8     head, the sequence number of the next record to be published
+//	24      8     tail, the sequence number of the oldest record not overwritten
+//	32      32    reserved
+//
+// followed by capacity slots, each is 8-byte aligned:
+//
+//	offset  size       field
+//	0       8          sequence, 2*n+1 while the record n is being written, 2*n+2 after
+//	8       slot size  the record encoded by an alignbinary.Codec
+//
+// The record n is stored in the slot n&(capacity-1). The producer never waits for
+// the consumers: it advances the tail before overwriting the oldest record,
+// and the consumers which fall behind skip the lost records.
+// The head, the tail and the sequences are accessed atomically, and the records are
+// copied by atomic 8-byte words, so a record is ordered between the odd and the even
+// sequences of its slot. The C writers and readers need the fences of testdata/ring.h.
+//
+// The file testdata/ring.h is a reference implementation in C11.
+//
+// A single record updated in place, e.g. stats or config, is shared by a SeqlockRegion.
+package shmring
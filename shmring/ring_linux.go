@@ -0,0 +1,256 @@
+//go:build linux
+
+package shmring
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+const (
+	magic      = 0x41425247
+	version    = 1
+	headerSize = 64
+	// seqSize is the size of the sequence at the start of a slot.
+	seqSize = 8
+)
+
+// errReadOnly is returned by publishing to a ring opened by Open.
+var errReadOnly = errors.New("shmring: ring is read-only")
+
+// header is the header at the start of the file, see the package documentation.
+type header struct {
+	Magic    uint32
+	Version  uint32
+	SlotSize uint32
+	Capacity uint32
+	Head     uint64
+	Tail     uint64
+	_        [32]byte
+}
+
+// Ring is a ring buffer of records of type T in a shared memory file.
+// Only one goroutine of all the processes sharing the file may call Publish,
+// any number of them may read the records by their own Readers.
+type Ring[T any] struct {
+	f      *os.File
+	c      *alignbinary.Codec
+	data   []byte
+	hdr    *header
+	size   int
+	stride int
+	mask   uint64
+	// head is the sequence number of the next record, owned by the producer.
+	head uint64
+	// scratch is the buffer of the producer to encode a record into.
+	scratch  words
+	writable bool
+}
+
+// Create creates or truncates the file at the path as an empty ring of capacity records,
+// which must be a power of two, and maps it for publishing. The records are encoded
+// by c, or a Codec of the host byte order and AlignDefault if c is nil.
+func Create[T any](path string, capacity int, c *alignbinary.Codec) (*Ring[T], error) {
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		return nil, fmt.Errorf("shmring: capacity %v is not a power of two", capacity)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRing[T](f, c, capacity, true)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.hdr.SlotSize = uint32(r.size)
+	r.hdr.Capacity = uint32(capacity)
+	r.hdr.Version = version
+	atomic.StoreUint32(&r.hdr.Magic, magic)
+	return r, nil
+}
+
+// Open maps the ring in the file at the path for reading, c must encode the
+// records as the producer does, or be nil as for Create.
+func Open[T any](path string, c *alignbinary.Codec) (*Ring[T], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var hdr header
+	buf := (*[headerSize]byte)(unsafe.Pointer(&hdr))
+	if _, err = f.ReadAt(buf[:], 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("shmring: cannot read the header of %v: %w", path, err)
+	}
+	if hdr.Magic != magic || hdr.Version != version {
+		f.Close()
+		return nil, fmt.Errorf("shmring: %v is not a ring of version %v", path, version)
+	}
+	r, err := newRing[T](f, c, int(hdr.Capacity), false)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if int(hdr.SlotSize) != r.size {
+		r.Close()
+		return nil, fmt.Errorf("shmring: slot size %v of %v doesn't match the record size %v", hdr.SlotSize, path, r.size)
+	}
+	return r, nil
+}
+
+// newRing maps the ring of capacity records in the file f,
+// which is extended to the size of the ring if it's writable.
+func newRing[T any](f *os.File, c *alignbinary.Codec, capacity int, writable bool) (*Ring[T], error) {
+	if c == nil {
+		c = alignbinary.NewCodec(alignbinary.WithProfile(alignbinary.ProfileNative))
+	}
+	if capacity <= 0 || capacity&(capacity-1) != 0 {
+		return nil, fmt.Errorf("shmring: capacity %v is not a power of two", capacity)
+	}
+	r := &Ring[T]{f: f, c: c, size: c.Size(new(T)), mask: uint64(capacity - 1), writable: writable}
+	if r.size == 0 {
+		return nil, fmt.Errorf("shmring: record of zero-size type %T", *new(T))
+	}
+	r.stride = (seqSize + r.size + 7) &^ 7
+	r.scratch = newWords(r.size)
+	size := headerSize + capacity*r.stride
+	prot := syscall.PROT_READ
+	if writable {
+		prot |= syscall.PROT_WRITE
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+	} else if fi, err := f.Stat(); err != nil {
+		return nil, err
+	} else if fi.Size() < int64(size) {
+		return nil, fmt.Errorf("shmring: size %v of %v is less than the ring of %v bytes", fi.Size(), f.Name(), size)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, prot, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, os.NewSyscallError("mmap", err)
+	}
+	r.data = data
+	r.hdr = (*header)(unsafe.Pointer(&data[0]))
+	r.head = atomic.LoadUint64(&r.hdr.Head)
+	return r, nil
+}
+
+// Capacity returns the number of slots.
+func (r *Ring[T]) Capacity() int {
+	return int(r.mask + 1)
+}
+
+// Head returns the sequence number of the next record to be published.
+func (r *Ring[T]) Head() uint64 {
+	return atomic.LoadUint64(&r.hdr.Head)
+}
+
+// Tail returns the sequence number of the oldest record which can be read.
+func (r *Ring[T]) Tail() uint64 {
+	return atomic.LoadUint64(&r.hdr.Tail)
+}
+
+// slot returns the sequence and the record of the slot of the record n.
+// The record is 8-byte aligned and padded to whole words within the slot.
+func (r *Ring[T]) slot(n uint64) (*uint64, unsafe.Pointer) {
+	p := unsafe.Pointer(&r.data[headerSize+int(n&r.mask)*r.stride])
+	return (*uint64)(p), unsafe.Add(p, seqSize)
+}
+
+// Publish writes rec as the next record, overwriting the oldest one if the ring is full.
+// It must only be called by the producer.
+//
+// The record is written with the atomic stores of words after the odd sequence,
+// and before the even one, so no reader sees it before the odd sequence.
+func (r *Ring[T]) Publish(rec *T) error {
+	if !r.writable {
+		return errReadOnly
+	}
+	if _, err := r.c.EncodeToPointer(r.scratch.ptr(), r.size, rec); err != nil {
+		return err
+	}
+	n := r.head
+	if n > r.mask {
+		// The record n-capacity is about to be overwritten.
+		atomic.StoreUint64(&r.hdr.Tail, n-r.mask)
+	}
+	seq, p := r.slot(n)
+	atomic.StoreUint64(seq, 2*n+1)
+	r.scratch.store(p)
+	atomic.StoreUint64(seq, 2*n+2)
+	r.head = n + 1
+	atomic.StoreUint64(&r.hdr.Head, n+1)
+	return nil
+}
+
+// Close unmaps the ring and closes the file.
+func (r *Ring[T]) Close() error {
+	err := syscall.Munmap(r.data)
+	r.data, r.hdr = nil, nil
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Reader reads the records of a Ring in order. A Reader is not safe for
+// concurrent use, each consumer should have its own.
+type Reader[T any] struct {
+	r    *Ring[T]
+	next uint64
+	lost uint64
+	buf  words
+}
+
+// NewReader returns a Reader starting from the oldest record in the ring.
+func (r *Ring[T]) NewReader() *Reader[T] {
+	return &Reader[T]{r: r, next: r.Tail(), buf: newWords(r.size)}
+}
+
+// Next decodes the next record into rec and reports whether there is one,
+// it returns false without waiting if all the published records are read.
+// The records overwritten before they're read are skipped, see Lost.
+func (rd *Reader[T]) Next(rec *T) bool {
+	for {
+		if rd.next >= rd.r.Head() {
+			return false
+		}
+		if tail := rd.r.Tail(); rd.next < tail {
+			rd.lost += tail - rd.next
+			rd.next = tail
+		}
+		seq, p := rd.r.slot(rd.next)
+		s := atomic.LoadUint64(seq)
+		if s != 2*rd.next+2 {
+			// The slot is being overwritten by a later record,
+			// whose producer has advanced the tail.
+			continue
+		}
+		// The atomic loads of words complete before the sequence is checked again.
+		rd.buf.load(p)
+		if atomic.LoadUint64(seq) != s {
+			continue
+		}
+		rd.next++
+		// The buf holds the whole record, so it can't fail.
+		rd.r.c.DecodeFromPointer(rd.buf.ptr(), rd.r.size, rec)
+		return true
+	}
+}
+
+// Seq returns the sequence number of the next record to be read.
+func (rd *Reader[T]) Seq() uint64 {
+	return rd.next
+}
+
+// Lost returns the number of records overwritten before they were read.
+func (rd *Reader[T]) Lost() uint64 {
+	return rd.lost
+}
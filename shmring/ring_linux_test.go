@@ -0,0 +1,173 @@
+//go:build linux
+
+package shmring
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// sample has the layout of struct sample in testdata/producer.c.
+type sample struct {
+	Channel uint8
+	Values  [2]uint32
+	Time    uint64
+}
+
+func newSample(i uint64) sample {
+	return sample{uint8(i % 4), [2]uint32{uint32(i), uint32(2 * i)}, 1000 * i}
+}
+
+func TestRing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r, err := Create[sample](path, 8, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	c, err := Open[sample](path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rd := c.NewReader()
+	var rec sample
+	if rd.Next(&rec) {
+		t.Errorf("TestRing: have a record in an empty ring")
+	}
+	for i := uint64(0); i < 5; i++ {
+		s := newSample(i)
+		r.Publish(&s)
+	}
+	for i := uint64(0); i < 5; i++ {
+		if !rd.Next(&rec) || rec != newSample(i) {
+			t.Fatalf("TestRing: have %v at %v, want %v", rec, i, newSample(i))
+		}
+	}
+	// Overwrite the records 5 to 11 before they are read.
+	for i := uint64(5); i < 20; i++ {
+		s := newSample(i)
+		r.Publish(&s)
+	}
+	if !rd.Next(&rec) || rec != newSample(12) || rd.Lost() != 7 {
+		t.Errorf("TestRing: have %v, lost %v, want %v, lost 7", rec, rd.Lost(), newSample(12))
+	}
+	if c.Head() != 20 || c.Tail() != 12 {
+		t.Errorf("TestRing: have head %v, tail %v, want 20, 12", c.Head(), c.Tail())
+	}
+	if err = c.Publish(&rec); err != errReadOnly {
+		t.Errorf("TestRing: have %v, want %v", err, errReadOnly)
+	}
+	if _, err = Open[uint32](path, nil); err == nil {
+		t.Errorf("TestRing: have no error for a mismatched slot size")
+	}
+	if _, err = Create[sample](path+"2", 6, nil); err == nil {
+		t.Errorf("TestRing: have no error for a capacity not a power of two")
+	}
+	s := newSample(1)
+	if allocs := testing.AllocsPerRun(100, func() {
+		r.Publish(&s)
+		rd.Next(&rec)
+	}); allocs != 0 {
+		t.Errorf("TestRing: have %v allocations per record, want 0", allocs)
+	}
+}
+
+func TestRingConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ring")
+	r, err := Create[sample](path, 16, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	const count = 100000
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		rd := r.NewReader()
+		go func() {
+			defer wg.Done()
+			var rec sample
+			var n uint64
+			for rd.Seq() < count {
+				if !rd.Next(&rec) {
+					continue
+				}
+				if want := newSample(rd.Seq() - 1); rec != want {
+					t.Errorf("TestRingConcurrent: have %v, want %v", rec, want)
+					return
+				}
+				n++
+			}
+			if n+rd.Lost() != count {
+				t.Errorf("TestRingConcurrent: have %v read and %v lost, want %v in total", n, rd.Lost(), count)
+			}
+		}()
+	}
+	for i := uint64(0); i < count; i++ {
+		s := newSample(i)
+		r.Publish(&s)
+	}
+	wg.Wait()
+}
+
+// buildC builds the C program testdata/name.c, or skips the test if there is no C compiler.
+func buildC(t *testing.T, name string) string {
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skipf("%v: no C compiler", t.Name())
+	}
+	bin := filepath.Join(t.TempDir(), name)
+	if out, err := exec.Command(cc, "-std=c11", "-D_DEFAULT_SOURCE", "-o", bin, filepath.Join("testdata", name+".c")).CombinedOutput(); err != nil {
+		t.Fatalf("%v: %v\n%s", t.Name(), err, out)
+	}
+	return bin
+}
+
+func TestCProducer(t *testing.T) {
+	bin := buildC(t, "producer")
+	path := filepath.Join(t.TempDir(), "ring")
+	if out, err := exec.Command(bin, path, "64", "100").CombinedOutput(); err != nil {
+		t.Fatalf("TestCProducer: %v\n%s", err, out)
+	}
+	r, err := Open[sample](path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	rd := r.NewReader()
+	var have []sample
+	var rec sample
+	for rd.Next(&rec) {
+		have = append(have, rec)
+	}
+	var want []sample
+	for i := uint64(36); i < 100; i++ {
+		want = append(want, newSample(i))
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("TestCProducer: have %v, want %v", have, want)
+	}
+}
+
+func TestCConsumer(t *testing.T) {
+	bin := buildC(t, "consumer")
+	path := filepath.Join(t.TempDir(), "ring")
+	r, err := Create[sample](path, 64, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for i := uint64(0); i < 100; i++ {
+		s := newSample(i)
+		r.Publish(&s)
+	}
+	out, err := exec.Command(bin, path).CombinedOutput()
+	if err != nil || string(out) != "read 64 lost 36\n" {
+		t.Errorf("TestCConsumer: have %q, %v, want \"read 64 lost 36\\n\"", out, err)
+	}
+}
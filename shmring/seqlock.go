@@ -0,0 +1,99 @@
+package shmring
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+// SeqlockHeaderSize is the size of the header of a SeqlockRegion, in bytes.
+const SeqlockHeaderSize = 8
+
+// SeqlockRegion is a record of type T in a shared memory region updated in place
+// by a single writer and protected by a sequence lock. The region has the layout:
+//
+//	offset  size         field
+//	0       8            sequence, odd while the record is being written
+//	8       record size  the record encoded by an alignbinary.Codec, padded to 8 bytes
+//
+// The sequence is in the byte order of the host and accessed atomically, and the record
+// is copied by atomic 8-byte words, so it's ordered between the odd and the even sequences.
+// The file testdata/seqlock.h is a reference implementation in C11.
+//
+// A SeqlockRegion is not safe for concurrent use, each reader and the writer
+// should have its own of the same region.
+type SeqlockRegion[T any] struct {
+	rec  unsafe.Pointer
+	seq  *uint64
+	c    *alignbinary.Codec
+	size int
+	// buf is the scratch buffer to encode or decode the record.
+	buf words
+}
+
+// NewSeqlockRegion returns a SeqlockRegion of the data, e.g. a shared memory mapping,
+// which must be 8-byte aligned and hold the header and the padded record.
+// The record is encoded by c, or a Codec of the host byte order and AlignDefault if c is nil.
+func NewSeqlockRegion[T any](data []byte, c *alignbinary.Codec) (*SeqlockRegion[T], error) {
+	if c == nil {
+		c = alignbinary.NewCodec(alignbinary.WithProfile(alignbinary.ProfileNative))
+	}
+	r := &SeqlockRegion[T]{c: c, size: c.Size(new(T))}
+	if r.size == 0 {
+		return nil, fmt.Errorf("shmring: record of zero-size type %T", *new(T))
+	}
+	r.buf = newWords(r.size)
+	if len(data) < r.Size() {
+		return nil, fmt.Errorf("shmring: seqlock region of %v bytes is less than %v bytes", len(data), r.Size())
+	}
+	if uintptr(unsafe.Pointer(&data[0]))%8 != 0 {
+		return nil, errors.New("shmring: seqlock region is not 8-byte aligned")
+	}
+	r.seq = (*uint64)(unsafe.Pointer(&data[0]))
+	r.rec = unsafe.Pointer(&data[SeqlockHeaderSize])
+	return r, nil
+}
+
+// Size returns the size of the region, in bytes.
+func (r *SeqlockRegion[T]) Size() int {
+	return SeqlockHeaderSize + 8*len(r.buf)
+}
+
+// Load decodes a consistent snapshot of the record into rec. It retries while
+// the record is being written, or if it was changed during copying.
+func (r *SeqlockRegion[T]) Load(rec *T) {
+	for {
+		s := atomic.LoadUint64(r.seq)
+		if s&1 != 0 {
+			runtime.Gosched()
+			continue
+		}
+		// The atomic loads of words complete before the sequence is checked again.
+		r.buf.load(r.rec)
+		if atomic.LoadUint64(r.seq) == s {
+			// The buf holds the whole record, so it can't fail.
+			r.c.DecodeFromPointer(r.buf.ptr(), r.size, rec)
+			return
+		}
+	}
+}
+
+// Store writes rec as the record. It must only be called by the writer.
+func (r *SeqlockRegion[T]) Store(rec *T) error {
+	if _, err := r.c.EncodeToPointer(r.buf.ptr(), r.size, rec); err != nil {
+		return err
+	}
+	atomic.AddUint64(r.seq, 1)
+	r.buf.store(r.rec)
+	atomic.AddUint64(r.seq, 1)
+	return nil
+}
+
+// Seq returns the current sequence, which is increased by 2 by every Store.
+func (r *SeqlockRegion[T]) Seq() uint64 {
+	return atomic.LoadUint64(r.seq)
+}
@@ -0,0 +1,114 @@
+//go:build linux
+
+package shmring
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// stats has the layout of struct stats in testdata/seqlock_writer.c.
+type stats struct {
+	Count uint32
+	Flag  uint8
+	Total uint64
+	Hist  [3]uint16
+}
+
+func newStats(i uint64) stats {
+	return stats{uint32(i), uint8(i), 3 * i, [3]uint16{uint16(i), uint16(i), uint16(i)}}
+}
+
+// checkSnapshots loads the region until its count reaches count, and reports
+// the snapshots which are not consistent.
+func checkSnapshots(t *testing.T, r *SeqlockRegion[stats], count uint64) {
+	var s stats
+	for s.Count < uint32(count) {
+		r.Load(&s)
+		if s != newStats(uint64(s.Count)) {
+			t.Errorf("%v: have an inconsistent snapshot %v", t.Name(), s)
+			return
+		}
+	}
+}
+
+func TestSeqlockRegion(t *testing.T) {
+	data, err := syscall.Mmap(-1, 0, 4096, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED|syscall.MAP_ANON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(data)
+	r, err := NewSeqlockRegion[stats](data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Size() != 32 {
+		t.Errorf("TestSeqlockRegion: have size %v, want 32", r.Size())
+	}
+	const count = 100000
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		rr, _ := NewSeqlockRegion[stats](data, nil)
+		go func() {
+			defer wg.Done()
+			checkSnapshots(t, rr, count)
+		}()
+	}
+	for i := uint64(1); i <= count; i++ {
+		s := newStats(i)
+		r.Store(&s)
+	}
+	wg.Wait()
+	if r.Seq() != 2*count {
+		t.Errorf("TestSeqlockRegion: have sequence %v, want %v", r.Seq(), 2*count)
+	}
+	s := newStats(1)
+	if allocs := testing.AllocsPerRun(100, func() {
+		r.Store(&s)
+		r.Load(&s)
+	}); allocs != 0 {
+		t.Errorf("TestSeqlockRegion: have %v allocations per snapshot, want 0", allocs)
+	}
+	if _, err = NewSeqlockRegion[stats](data[:31], nil); err == nil {
+		t.Errorf("TestSeqlockRegion: have no error for a short region")
+	}
+	if _, err = NewSeqlockRegion[stats](data[4:], nil); err == nil {
+		t.Errorf("TestSeqlockRegion: have no error for an unaligned region")
+	}
+}
+
+func TestCSeqlockWriter(t *testing.T) {
+	bin := buildC(t, "seqlock_writer")
+	path := filepath.Join(t.TempDir(), "stats")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err = f.Truncate(32); err != nil {
+		t.Fatal(err)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, 32, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Munmap(data)
+	r, err := NewSeqlockRegion[stats](data, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const count = 1000000
+	cmd := exec.Command(bin, path, "1000000")
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	checkSnapshots(t, r, count)
+	if err = cmd.Wait(); err != nil {
+		t.Errorf("TestCSeqlockWriter: %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+/*
+ * consumer reads all the samples in the ring at the path, checks them,
+ * and prints the number of samples read and lost, used by TestCConsumer:
+ *
+ *	consumer path
+ */
+#include <inttypes.h>
+#include <stdio.h>
+
+#include "ring.h"
+
+struct sample {
+	uint8_t channel;
+	uint32_t values[2];
+	uint64_t time;
+};
+
+int main(int argc, char **argv)
+{
+	struct ring r;
+	struct sample s;
+	uint64_t next = 0, lost = 0, n = 0;
+	if (argc != 2) {
+		fprintf(stderr, "usage: consumer path\n");
+		return 2;
+	}
+	if (ring_open(&r, argv[1]) < 0 || r.hdr->slot_size != sizeof(s)) {
+		fprintf(stderr, "consumer: cannot open %s\n", argv[1]);
+		return 1;
+	}
+	while (ring_read(&r, &next, &s, &lost)) {
+		uint64_t i = next - 1;
+		if (s.channel != i % 4 || s.values[0] != i || s.values[1] != 2 * i || s.time != 1000 * i) {
+			fprintf(stderr, "consumer: bad sample %" PRIu64 "\n", i);
+			return 1;
+		}
+		n++;
+	}
+	printf("read %" PRIu64 " lost %" PRIu64 "\n", n, lost);
+	ring_close(&r);
+	return 0;
+}
@@ -0,0 +1,37 @@
+/*
+ * producer publishes count samples to a new ring at the path,
+ * the samples are checked by TestCProducer:
+ *
+ *	producer path capacity count
+ */
+#include <stdio.h>
+#include <stdlib.h>
+
+#include "ring.h"
+
+struct sample {
+	uint8_t channel;
+	uint32_t values[2];
+	uint64_t time;
+};
+
+int main(int argc, char **argv)
+{
+	struct ring r;
+	uint64_t i, count;
+	if (argc != 4) {
+		fprintf(stderr, "usage: producer path capacity count\n");
+		return 2;
+	}
+	if (ring_create(&r, argv[1], sizeof(struct sample), (uint32_t)atoi(argv[2])) < 0) {
+		perror("ring_create");
+		return 1;
+	}
+	count = strtoull(argv[3], NULL, 10);
+	for (i = 0; i < count; i++) {
+		struct sample s = {(uint8_t)(i % 4), {(uint32_t)i, (uint32_t)(2 * i)}, 1000 * i};
+		ring_publish(&r, &s);
+	}
+	ring_close(&r);
+	return 0;
+}
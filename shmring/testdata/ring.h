@@ -0,0 +1,149 @@
+/*
+ * ring.h is a reference implementation in C11 of the ring buffer of package shmring,
+ * see its documentation for the layout of the file.
+ */
+#ifndef SHMRING_RING_H
+#define SHMRING_RING_H
+
+#include <fcntl.h>
+#include <stdatomic.h>
+#include <stddef.h>
+#include <stdint.h>
+#include <string.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#define RING_MAGIC 0x41425247u
+#define RING_VERSION 1u
+#define RING_HEADER_SIZE 64
+
+struct ring_header {
+	uint32_t magic;
+	uint32_t version;
+	uint32_t slot_size;
+	uint32_t capacity;
+	_Atomic uint64_t head;
+	_Atomic uint64_t tail;
+	uint8_t reserved[32];
+};
+
+_Static_assert(sizeof(struct ring_header) == RING_HEADER_SIZE, "ring header size");
+
+struct ring {
+	struct ring_header *hdr;
+	size_t size;
+	size_t stride;
+	/* head is the sequence number of the next record, owned by the producer. */
+	uint64_t head;
+};
+
+static inline size_t ring_stride(uint32_t slot_size)
+{
+	return (8 + (size_t)slot_size + 7) & ~(size_t)7;
+}
+
+static inline _Atomic uint64_t *ring_slot(const struct ring *r, uint64_t n)
+{
+	char *base = (char *)r->hdr + RING_HEADER_SIZE;
+	return (_Atomic uint64_t *)(base + (n & (r->hdr->capacity - 1)) * r->stride);
+}
+
+/* ring_create creates an empty ring at the path, capacity must be a power of two. */
+static inline int ring_create(struct ring *r, const char *path, uint32_t slot_size, uint32_t capacity)
+{
+	int fd = open(path, O_RDWR | O_CREAT | O_TRUNC, 0644);
+	if (fd < 0)
+		return -1;
+	r->stride = ring_stride(slot_size);
+	r->size = RING_HEADER_SIZE + capacity * r->stride;
+	if (ftruncate(fd, (off_t)r->size) < 0) {
+		close(fd);
+		return -1;
+	}
+	r->hdr = mmap(NULL, r->size, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
+	close(fd);
+	if (r->hdr == MAP_FAILED)
+		return -1;
+	r->head = 0;
+	r->hdr->slot_size = slot_size;
+	r->hdr->capacity = capacity;
+	r->hdr->version = RING_VERSION;
+	atomic_store((_Atomic uint32_t *)&r->hdr->magic, RING_MAGIC);
+	return 0;
+}
+
+/* ring_open maps an existing ring at the path for reading. */
+static inline int ring_open(struct ring *r, const char *path)
+{
+	struct ring_header hdr;
+	int fd = open(path, O_RDONLY);
+	if (fd < 0)
+		return -1;
+	if (pread(fd, &hdr, sizeof(hdr), 0) != sizeof(hdr) ||
+	    hdr.magic != RING_MAGIC || hdr.version != RING_VERSION) {
+		close(fd);
+		return -1;
+	}
+	r->stride = ring_stride(hdr.slot_size);
+	r->size = RING_HEADER_SIZE + hdr.capacity * r->stride;
+	r->hdr = mmap(NULL, r->size, PROT_READ, MAP_SHARED, fd, 0);
+	close(fd);
+	if (r->hdr == MAP_FAILED)
+		return -1;
+	r->head = 0;
+	return 0;
+}
+
+static inline void ring_close(struct ring *r)
+{
+	munmap(r->hdr, r->size);
+}
+
+/* ring_publish writes the record rec of slot_size bytes, it must only be called by the producer. */
+static inline void ring_publish(struct ring *r, const void *rec)
+{
+	uint64_t n = r->head;
+	_Atomic uint64_t *seq = ring_slot(r, n);
+	if (n >= r->hdr->capacity)
+		atomic_store(&r->hdr->tail, n - r->hdr->capacity + 1);
+	atomic_store_explicit(seq, 2 * n + 1, memory_order_relaxed);
+	/* The record must not be visible before the odd sequence. */
+	atomic_thread_fence(memory_order_release);
+	memcpy((char *)seq + 8, rec, r->hdr->slot_size);
+	atomic_store_explicit(seq, 2 * n + 2, memory_order_release);
+	r->head = n + 1;
+	atomic_store(&r->hdr->head, n + 1);
+}
+
+/*
+ * ring_read copies the record *next into rec and returns 1, or returns 0 if all the
+ * published records are read. The records overwritten before they're read are
+ * skipped and added to *lost.
+ */
+static inline int ring_read(const struct ring *r, uint64_t *next, void *rec, uint64_t *lost)
+{
+	for (;;) {
+		uint64_t tail, s;
+		_Atomic uint64_t *seq;
+		if (*next >= atomic_load(&r->hdr->head))
+			return 0;
+		tail = atomic_load(&r->hdr->tail);
+		if (*next < tail) {
+			*lost += tail - *next;
+			*next = tail;
+		}
+		seq = ring_slot(r, *next);
+		s = atomic_load_explicit(seq, memory_order_acquire);
+		if (s != 2 * *next + 2)
+			continue;
+		memcpy(rec, (char *)seq + 8, r->hdr->slot_size);
+		/* The copy must complete before the sequence is checked again. */
+		atomic_thread_fence(memory_order_acquire);
+		if (atomic_load_explicit(seq, memory_order_relaxed) != s)
+			continue;
+		(*next)++;
+		return 1;
+	}
+}
+
+#endif /* SHMRING_RING_H */
@@ -0,0 +1,43 @@
+/*
+ * seqlock.h is a reference implementation in C11 of the SeqlockRegion of package shmring:
+ * a sequence of 8 bytes followed by the record padded to 8 bytes, see its documentation.
+ */
+#ifndef SHMRING_SEQLOCK_H
+#define SHMRING_SEQLOCK_H
+
+#include <stdatomic.h>
+#include <stdint.h>
+#include <string.h>
+
+#define SEQLOCK_HEADER_SIZE 8
+
+struct seqlock_region {
+	_Atomic uint64_t seq;
+	unsigned char record[];
+};
+
+/* seqlock_store writes the record rec of size bytes, it must only be called by the writer. */
+static inline void seqlock_store(struct seqlock_region *r, const void *rec, size_t size)
+{
+	uint64_t s = atomic_load_explicit(&r->seq, memory_order_relaxed);
+	atomic_store_explicit(&r->seq, s + 1, memory_order_relaxed);
+	atomic_thread_fence(memory_order_release);
+	memcpy(r->record, rec, size);
+	atomic_store_explicit(&r->seq, s + 2, memory_order_release);
+}
+
+/* seqlock_load copies a consistent snapshot of the record of size bytes into rec. */
+static inline void seqlock_load(const struct seqlock_region *r, void *rec, size_t size)
+{
+	for (;;) {
+		uint64_t s = atomic_load_explicit((_Atomic uint64_t *)&r->seq, memory_order_acquire);
+		if (s & 1)
+			continue;
+		memcpy(rec, r->record, size);
+		atomic_thread_fence(memory_order_acquire);
+		if (atomic_load_explicit((_Atomic uint64_t *)&r->seq, memory_order_relaxed) == s)
+			return;
+	}
+}
+
+#endif /* SHMRING_SEQLOCK_H */
@@ -0,0 +1,50 @@
+/*
+ * seqlock_writer updates the stats in the seqlock region at the start of
+ * the existing file at the path count times, used by TestCSeqlockWriter:
+ *
+ *	seqlock_writer path count
+ */
+#include <fcntl.h>
+#include <stdio.h>
+#include <stdlib.h>
+#include <sys/mman.h>
+#include <unistd.h>
+
+#include "seqlock.h"
+
+struct stats {
+	uint32_t count;
+	uint8_t flag;
+	uint64_t total;
+	uint16_t hist[3];
+};
+
+int main(int argc, char **argv)
+{
+	struct seqlock_region *r;
+	size_t size = SEQLOCK_HEADER_SIZE + sizeof(struct stats);
+	uint64_t i, count;
+	int fd;
+	if (argc != 3) {
+		fprintf(stderr, "usage: seqlock_writer path count\n");
+		return 2;
+	}
+	fd = open(argv[1], O_RDWR);
+	if (fd < 0) {
+		perror("open");
+		return 1;
+	}
+	r = mmap(NULL, size, PROT_READ | PROT_WRITE, MAP_SHARED, fd, 0);
+	close(fd);
+	if (r == MAP_FAILED) {
+		perror("mmap");
+		return 1;
+	}
+	count = strtoull(argv[2], NULL, 10);
+	for (i = 1; i <= count; i++) {
+		struct stats s = {(uint32_t)i, (uint8_t)i, 3 * i, {(uint16_t)i, (uint16_t)i, (uint16_t)i}};
+		seqlock_store(r, &s, sizeof(s));
+	}
+	munmap(r, size);
+	return 0;
+}
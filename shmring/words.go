@@ -0,0 +1,36 @@
+package shmring
+
+import (
+	"sync/atomic"
+	"unsafe"
+)
+
+// The records are copied to and from the shared memory word by word with
+// the atomic operations, so the copy is ordered with the atomic accesses of
+// the sequence before and after it, which a plain copy isn't.
+
+// words is a scratch buffer of the record of n bytes, rounded up to 8-byte words.
+type words []uint64
+
+func newWords(n int) words {
+	return make(words, (n+7)/8)
+}
+
+// ptr returns the pointer to the buffer.
+func (w words) ptr() unsafe.Pointer {
+	return unsafe.Pointer(&w[0])
+}
+
+// load copies the words at p into w.
+func (w words) load(p unsafe.Pointer) {
+	for i := range w {
+		w[i] = atomic.LoadUint64((*uint64)(unsafe.Add(p, 8*i)))
+	}
+}
+
+// store copies w into the words at p.
+func (w words) store(p unsafe.Pointer) {
+	for i := range w {
+		atomic.StoreUint64((*uint64)(unsafe.Add(p, 8*i)), w[i])
+	}
+}
@@ -0,0 +1,165 @@
+package alignbinary
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// zeroPad is the source of the padding bytes written by StreamEncoder.AlignTo.
+var zeroPad [64]byte
+
+// StreamEncoder writes the binary representation of messages into an output stream.
+// It owns a buffered writer and a scratch buffer which are reused between messages.
+//
+// Each message starts at an offset of the stream aligned to the alignment of its type,
+// as if the messages were fields of a struct, and the gaps are filled with zeros.
+//
+// A StreamEncoder is not safe for concurrent use by multiple goroutines.
+type StreamEncoder struct {
+	w     *bufio.Writer
+	order binary.ByteOrder
+	eg    *EncoderGroup
+	// buf is the scratch buffer to encode a message.
+	buf []byte
+	// offset is the number of bytes written so far.
+	offset int64
+}
+
+// NewStreamEncoder returns a new encoder that writes into w using the specified
+// byte order and encoder group. If eg is nil, the default encoder group is used.
+func NewStreamEncoder(w io.Writer, order binary.ByteOrder, eg *EncoderGroup) *StreamEncoder {
+	if eg == nil {
+		eg = DefaultEncoderGroup()
+	}
+	return &StreamEncoder{w: bufio.NewWriter(w), order: order, eg: eg}
+}
+
+// Encode writes the binary representation of msg into the stream.
+// The data may stay in the internal buffer until Flush is called.
+//
+// Msg must be a fixed-size value, a pointer to a fixed-size value,
+// or a slice of fixed-size values.
+func (e *StreamEncoder) Encode(msg interface{}) error {
+	if err := e.AlignTo(msgAlign(msg, e.eg.af)); err != nil {
+		return err
+	}
+	var n int
+	var err error
+	e.buf, n, err = e.eg.write(e.w, e.order, msg, e.buf)
+	e.offset += int64(n)
+	return err
+}
+
+// AlignTo writes zero padding bytes into the stream until the offset
+// is a multiple of n. It panics if n is not positive.
+func (e *StreamEncoder) AlignTo(n int) error {
+	if n <= 0 {
+		panic(fmt.Sprintf("alignbinary: invalid alignment: %v", n))
+	}
+	for pad := padLen(e.offset, n); pad > 0; {
+		m := pad
+		if m > len(zeroPad) {
+			m = len(zeroPad)
+		}
+		m, err := e.w.Write(zeroPad[:m])
+		e.offset += int64(m)
+		if err != nil {
+			return err
+		}
+		pad -= m
+	}
+	return nil
+}
+
+// Flush writes any buffered data into the underlying io.Writer.
+func (e *StreamEncoder) Flush() error {
+	return e.w.Flush()
+}
+
+// Offset returns the number of bytes written into the stream so far,
+// including the ones still buffered.
+func (e *StreamEncoder) Offset() int64 {
+	return e.offset
+}
+
+// StreamDecoder reads and decodes messages from an input stream.
+// It owns a buffered reader and a scratch buffer which are reused between messages.
+//
+// Each message is expected at an offset of the stream aligned to the alignment of its type,
+// the padding bytes before it are skipped.
+//
+// The StreamDecoder may read data from r beyond the messages decoded.
+// A StreamDecoder is not safe for concurrent use by multiple goroutines.
+type StreamDecoder struct {
+	r     *bufio.Reader
+	order binary.ByteOrder
+	dg    *DecoderGroup
+	// buf is the scratch buffer to decode a message.
+	buf []byte
+	// offset is the number of bytes decoded so far.
+	offset int64
+}
+
+// NewStreamDecoder returns a new decoder that reads from r using the specified
+// byte order and decoder group. If dg is nil, the default decoder group is used.
+func NewStreamDecoder(r io.Reader, order binary.ByteOrder, dg *DecoderGroup) *StreamDecoder {
+	if dg == nil {
+		dg = DefaultDecoderGroup()
+	}
+	return &StreamDecoder{r: bufio.NewReader(r), order: order, dg: dg}
+}
+
+// Decode reads the next message from the stream and stores it in msg.
+// It returns io.EOF if the stream ends before the message,
+// and a *DecodeError wrapping io.ErrUnexpectedEOF if it ends in the middle
+// of the message or its leading padding.
+//
+// Msg must be a pointer to a fixed-size value or a slice of fixed-size values.
+func (d *StreamDecoder) Decode(msg interface{}) error {
+	if err := d.AlignTo(msgAlign(msg, d.dg.af)); err != nil {
+		if de, ok := err.(*DecodeError); ok {
+			return d.dg.decodeError(msg, d.dg.msgSize(msg), 0, de.Err)
+		}
+		return err
+	}
+	var n int
+	var err error
+	d.buf, n, err = d.dg.read(d.r, d.order, msg, d.buf)
+	d.offset += int64(n)
+	return err
+}
+
+// Offset returns the number of bytes consumed from the stream so far.
+func (d *StreamDecoder) Offset() int64 {
+	return d.offset
+}
+
+// AlignTo skips the padding bytes in the stream until the offset is a multiple of n.
+// It returns io.EOF if the stream ends before the padding, and a *DecodeError
+// without Type wrapping io.ErrUnexpectedEOF if it ends in the middle of the padding,
+// whose Offset is the one of the padding in the stream. It panics if n is not positive.
+func (d *StreamDecoder) AlignTo(n int) error {
+	if n <= 0 {
+		panic(fmt.Sprintf("alignbinary: invalid alignment: %v", n))
+	}
+	offset, pad := d.offset, padLen(d.offset, n)
+	m, err := d.r.Discard(pad)
+	d.offset += int64(m)
+	if err == nil || err == io.EOF && m == 0 {
+		return err
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return &DecodeError{Offset: offset, Need: pad, Have: m, Err: err}
+}
+
+// Buffered returns a reader of the data remaining in the decoder's buffer.
+func (d *StreamDecoder) Buffered() io.Reader {
+	n := d.r.Buffered()
+	data, _ := d.r.Peek(n)
+	return bytes.NewReader(data)
+}
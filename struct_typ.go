@@ -2,6 +2,7 @@ package alignbinary
 
 import (
 	"reflect"
+	"sync"
 )
 
 const (
@@ -113,6 +114,54 @@ func calcSizeAlign(t reflect.Type, af AlignFactor) (uintptr, uint8) {
 	}
 }
 
+// alignKey is the key of the alignCache.
+type alignKey struct {
+	t  reflect.Type
+	af AlignFactor
+}
+
+// alignCache caches the alignments calculated by typeAlign.
+var alignCache sync.Map
+
+// typeAlign returns the alignment in bytes of t based on the given af.
+func typeAlign(t reflect.Type, af AlignFactor) int {
+	if af == AlignDefault {
+		return t.Align()
+	}
+	key := alignKey{t, af}
+	if val, ok := alignCache.Load(key); ok {
+		return val.(int)
+	}
+	_, a := calcSizeAlign(t, af)
+	alignCache.Store(key, int(a))
+	return int(a)
+}
+
+// typeSize returns the size in bytes of t based on the given af.
+func typeSize(t reflect.Type, af AlignFactor) uintptr {
+	if af == AlignDefault {
+		return t.Size()
+	}
+	size, _ := calcSizeAlign(t, af)
+	return size
+}
+
+// msgAlign returns the alignment in bytes of msg based on the given af.
+// The alignment of a pointer or a slice is the one of its element.
+func msgAlign(msg interface{}, af AlignFactor) int {
+	t := reflect.TypeOf(msg)
+	if k := t.Kind(); k == reflect.Ptr || k == reflect.Slice {
+		t = t.Elem()
+	}
+	return typeAlign(t, af)
+}
+
+// padLen returns the number of padding bytes to insert at the offset
+// so that the next data starts at a multiple of n.
+func padLen(offset int64, n int) int {
+	return int((int64(n) - offset%int64(n)) % int64(n))
+}
+
 // align returns the result of rounding x up to a multiple of n.
 // n must be a power of two.
 func align(x, n uintptr) uintptr {
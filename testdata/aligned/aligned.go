@@ -0,0 +1,12 @@
+// Package aligned has the types with the methods generated by 'alignbinary gen-marshal'
+// for the tests of the package alignbinary.
+package aligned
+
+//go:generate go run ../../cmd/alignbinary gen-marshal -align 1 -type Sequence
+
+// Sequence has the layout of the sequenceStruct of the tests.
+type Sequence struct {
+	Hdr     uint8
+	Body    [2]uint32
+	Trailer uint16
+}
@@ -0,0 +1,44 @@
+// Code generated by "alignbinary gen-marshal -align 1 -type Sequence"; DO NOT EDIT.
+
+package aligned
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/happyxcj/alignbinary"
+)
+
+// AlignedLayout implements the alignbinary.AlignedMarshaler and the alignbinary.AlignedUnmarshaler.
+func (Sequence) AlignedLayout() (alignbinary.AlignFactor, int) {
+	return alignbinary.Align1Byte, 11
+}
+
+// AppendAligned implements the alignbinary.AlignedMarshaler.
+func (m Sequence) AppendAligned(buf []byte, order binary.ByteOrder) []byte {
+	n := len(buf)
+	buf = append(buf, make([]byte, 11)...)
+	b := buf[n:]
+	b[0] = byte(m.Hdr)
+	order.PutUint32(b[1:], uint32(m.Body[0]))
+	order.PutUint32(b[5:], uint32(m.Body[1]))
+	order.PutUint16(b[9:], uint16(m.Trailer))
+	return buf
+}
+
+// MarshalAligned returns the binary representation of m.
+func (m Sequence) MarshalAligned(order binary.ByteOrder) []byte {
+	return m.AppendAligned(make([]byte, 0, 11), order)
+}
+
+// UnmarshalAligned implements the alignbinary.AlignedUnmarshaler.
+func (m *Sequence) UnmarshalAligned(b []byte, order binary.ByteOrder) error {
+	if len(b) < 11 {
+		return io.ErrUnexpectedEOF
+	}
+	m.Hdr = uint8(b[0])
+	m.Body[0] = uint32(order.Uint32(b[1:]))
+	m.Body[1] = uint32(order.Uint32(b[5:]))
+	m.Trailer = uint16(order.Uint16(b[9:]))
+	return nil
+}
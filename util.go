@@ -4,6 +4,7 @@ import (
 	"math"
 	"unsafe"
 	"fmt"
+	"sync"
 )
 
 func boolToUint8(v bool) uint8 {
@@ -64,6 +65,67 @@ func offsetPtr(ptr unsafe.Pointer, off uintptr) unsafe.Pointer {
 	return unsafe.Pointer(uintptr(ptr) + off)
 }
 
+// chunkSize is the maximum size in bytes of the buffer used to write or read
+// a slice or an array, larger ones are streamed in chunks of whole elements.
+const chunkSize = 64 << 10
+
+// chunkLen returns the number of elements of eleSize bytes in a chunk
+// when streaming a list of num elements. A chunk holds at least one element.
+func chunkLen(num, eleSize int) int {
+	if eleSize == 0 {
+		return num
+	}
+	n := chunkSize / eleSize
+	if n == 0 {
+		n = 1
+	}
+	if n > num {
+		n = num
+	}
+	return n
+}
+
+// grow returns a zeroed slice of n bytes, which reuses the buf if it has enough capacity.
+func grow(buf []byte, n int) []byte {
+	if cap(buf) < n {
+		return make([]byte, n)
+	}
+	buf = buf[:n]
+	for i := range buf {
+		buf[i] = 0
+	}
+	return buf
+}
+
+// parallelThreshold is the minimum size in bytes of the chunk of a slice encoded
+// or decoded by a goroutine, smaller slices are processed sequentially.
+const parallelThreshold = 1 << 20
+
+// parallelWorkers returns the number of goroutines to process a slice of size bytes,
+// up to the given parallelism.
+func parallelWorkers(size, parallelism int) int {
+	if n := size / parallelThreshold; n < parallelism {
+		return n
+	}
+	return parallelism
+}
+
+// parallelRange splits the num elements into the given number of element-aligned
+// ranges [i, j), calls fn with each of them in its own goroutine and waits for them.
+func parallelRange(num, workers int, fn func(i, j int)) {
+	var wg sync.WaitGroup
+	per := (num + workers - 1) / workers
+	for i := 0; i < num; i += per {
+		j := min(i+per, num)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fn(i, j)
+		}()
+	}
+	wg.Wait()
+}
+
 func checkAlignFactor(af AlignFactor) {
 	if af > 8 || af&(af-1) != 0 {
 		panic(fmt.Sprintf("alignbinary: invalid alignment factor: %v",af))
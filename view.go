@@ -0,0 +1,73 @@
+package alignbinary
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"unsafe"
+)
+
+// View returns a pointer to the value of type T encoded in the data by AlignDefault
+// and the byte order of the host, without copying. The value shares the memory
+// with the data, so the changes of either are visible through the other.
+//
+// It returns an error if the binary representation of T is not identical to its
+// memory layout, e.g. T contains a bool, or if the data isn't aligned as T is
+// in memory, see AlignedBytes. It returns a *DecodeError wrapping io.ErrUnexpectedEOF
+// if the data is too short.
+func View[T any](data []byte) (*T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if err := checkView(t, data); err != nil {
+		return nil, err
+	}
+	if size := int(t.Size()); len(data) < size {
+		path, offset := locateField(t, AlignDefault, 0, len(data))
+		return nil, &DecodeError{Offset: int64(offset), Need: size, Have: len(data), FieldPath: path, Type: t, Err: io.ErrUnexpectedEOF}
+	}
+	return (*T)(unsafe.Pointer(unsafe.SliceData(data))), nil
+}
+
+// ViewSlice is like View but returns a slice of the values of type T back to back
+// in the data, which must be a whole number of them.
+// It returns a *TrailingDataError if there are trailing bytes after the last value.
+func ViewSlice[T any](data []byte) ([]T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if err := checkView(t, data); err != nil {
+		return nil, err
+	}
+	size := int(t.Size())
+	if size == 0 {
+		return nil, fmt.Errorf("alignbinary: cannot view a slice of zero-size type %v", t)
+	}
+	n := len(data) / size
+	if n*size != len(data) {
+		return nil, &TrailingDataError{Size: n * size, Have: len(data), Type: reflect.SliceOf(t)}
+	}
+	return unsafe.Slice((*T)(unsafe.Pointer(unsafe.SliceData(data))), n), nil
+}
+
+// checkView returns an error if the data can't be viewed as values of type t.
+func checkView(t reflect.Type, data []byte) error {
+	if err := checkType(t, t.String()); err != nil {
+		return err
+	}
+	if err := checkSameLayout(t, AlignDefault, t.String()); err != nil {
+		return err
+	}
+	if p := uintptr(unsafe.Pointer(unsafe.SliceData(data))); p%uintptr(t.Align()) != 0 {
+		return fmt.Errorf("alignbinary: cannot view %v at address %#x not aligned by %v bytes", t, p, t.Align())
+	}
+	return nil
+}
+
+// AlignedBytes returns a zeroed slice of n bytes aligned as the type T is in memory,
+// which can be viewed by View and ViewSlice once it holds the data.
+func AlignedBytes[T any](n int) []byte {
+	a := int(unsafe.Alignof(*new(T)))
+	buf := make([]byte, n+a-1)
+	off := 0
+	if r := int(uintptr(unsafe.Pointer(unsafe.SliceData(buf))) % uintptr(a)); r != 0 {
+		off = a - r
+	}
+	return buf[off : off+n : off+n]
+}